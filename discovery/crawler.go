@@ -0,0 +1,362 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/zauberhaus/go-electrum/electrum"
+	"github.com/zauberhaus/logger"
+)
+
+const (
+	// DefaultConcurrency is the number of servers checked in parallel.
+	DefaultConcurrency = 8
+
+	// DefaultMaxServers is the number of candidates a crawl checks at most.
+	DefaultMaxServers = 200
+
+	// DefaultCheckTimeout limits the time to connect to and check a single server.
+	DefaultCheckTimeout = 15 * time.Second
+
+	// latencyScale is the latency at which a server scores 0.5
+	latencyScale = 250 * time.Millisecond
+)
+
+var (
+	// ErrWrongGenesis throws an error if a server serves a different chain.
+	ErrWrongGenesis = errors.New("server has a different genesis hash")
+
+	// ErrWrongChain throws an error if a server does not follow the checkpoints of
+	// the network, e.g. a Bitcoin Cash server for Bitcoin.
+	ErrWrongChain = errors.New("server follows a different chain")
+
+	// ErrNoPort throws an error if a server offers neither TCP nor SSL.
+	ErrNoPort = errors.New("server has no usable port")
+)
+
+// ConnectFunc opens a client connection to server.
+type ConnectFunc func(ctx context.Context, server Server) (*electrum.Client, error)
+
+// Option configures optional behaviour of a Crawler.
+type Option func(*Crawler)
+
+// WithConnect replaces the function connecting to the candidates.
+func WithConnect(connect ConnectFunc) Option {
+	return func(c *Crawler) {
+		c.connect = connect
+	}
+}
+
+// WithClientOptions passes opts to the clients of the default connect function,
+// e.g. a Tor dialer or a trust store.
+func WithClientOptions(opts ...electrum.ClientOption) Option {
+	return func(c *Crawler) {
+		c.clientOpts = append(c.clientOpts, opts...)
+	}
+}
+
+// WithConcurrency sets the number of servers checked in parallel.
+func WithConcurrency(n int) Option {
+	return func(c *Crawler) {
+		c.concurrency = max(n, 1)
+	}
+}
+
+// WithMaxServers limits the number of candidates a crawl checks.
+func WithMaxServers(n int) Option {
+	return func(c *Crawler) {
+		c.maxServers = n
+	}
+}
+
+// WithCheckTimeout limits the time to connect to and check a single server.
+func WithCheckTimeout(d time.Duration) Option {
+	return func(c *Crawler) {
+		c.timeout = d
+	}
+}
+
+// WithOnion includes Tor hidden services. They are skipped by default, because
+// they need a Tor dialer to connect.
+func WithOnion(enable bool) Option {
+	return func(c *Crawler) {
+		c.onion = enable
+	}
+}
+
+// Crawler discovers the servers of a network by following the peer lists of the
+// servers it knows.
+type Crawler struct {
+	network Network
+
+	connect     ConnectFunc
+	clientOpts  []electrum.ClientOption
+	concurrency int
+	maxServers  int
+	timeout     time.Duration
+	onion       bool
+}
+
+// NewCrawler initialize a new crawler for network.
+func NewCrawler(network Network, opts ...Option) *Crawler {
+	c := &Crawler{
+		network:     network,
+		concurrency: DefaultConcurrency,
+		maxServers:  DefaultMaxServers,
+		timeout:     DefaultCheckTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.connect == nil {
+		c.connect = c.dial
+	}
+
+	return c
+}
+
+// dial prefers SSL and falls back to TCP. Peers use self-signed certificates, so
+// the certificate is not verified unless a trust store is configured. The chain
+// checks protect against servers of other chains, not against a man in the middle.
+func (c *Crawler) dial(ctx context.Context, server Server) (*electrum.Client, error) {
+	if addr := server.SSLAddr(); addr != "" {
+		config := &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         server.Host,
+		}
+
+		client, err := electrum.NewClientSSL(ctx, addr, config, c.clientOpts...)
+		if err == nil || server.TCPPort == 0 {
+			return client, err
+		}
+	}
+
+	if addr := server.TCPAddr(); addr != "" {
+		return electrum.NewClientTCP(ctx, addr, c.clientOpts...)
+	}
+
+	return nil, ErrNoPort
+}
+
+// Crawl checks seeds and all peers reachable from them and returns the verified
+// servers ordered by score. If seeds is empty the bundled seeds of the network are used.
+func (c *Crawler) Crawl(ctx context.Context, seeds ...Server) ([]Server, error) {
+	log := logger.GetLogger(ctx)
+
+	if len(seeds) == 0 {
+		var err error
+		if seeds, err = Seeds(c.network); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		lock     sync.Mutex
+		wg       sync.WaitGroup
+		seen     = make(map[string]bool)
+		verified []Server
+		sem      = make(chan struct{}, c.concurrency)
+	)
+
+	var visit func(server Server)
+	visit = func(server Server) {
+		lock.Lock()
+		if seen[server.key()] || len(seen) >= c.maxServers || (server.IsOnion() && !c.onion) {
+			lock.Unlock()
+			return
+		}
+		seen[server.key()] = true
+		lock.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			checked, peers, err := c.check(ctx, server)
+			<-sem
+
+			if err != nil {
+				log.Debugf("Rejected %s: %v", server.Host, err)
+				return
+			}
+
+			lock.Lock()
+			verified = append(verified, checked)
+			lock.Unlock()
+
+			for _, peer := range peers {
+				visit(peer)
+			}
+		}()
+	}
+
+	for _, seed := range seeds {
+		visit(seed)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(verified, func(a, b Server) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+
+			return 1
+		}
+
+		return strings.Compare(a.Host, b.Host)
+	})
+
+	return verified, nil
+}
+
+// check connects to server, verifies its genesis hash and checkpoints and returns
+// the updated server together with its peers.
+func (c *Crawler) check(ctx context.Context, server Server) (Server, []Server, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	client, err := c.connect(ctx, server)
+	if err != nil {
+		return server, nil, err
+	}
+	defer client.Shutdown()
+
+	if _, _, err := client.ServerVersion(ctx); err != nil {
+		return server, nil, err
+	}
+
+	start := time.Now()
+	features, err := client.ServerFeatures(ctx)
+	if err != nil {
+		return server, nil, err
+	}
+	latency := time.Since(start)
+
+	if features == nil || !strings.EqualFold(features.GenesisHash, c.network.GenesisHash) {
+		return server, nil, ErrWrongGenesis
+	}
+
+	if err := c.checkpoints(ctx, client); err != nil {
+		return server, nil, err
+	}
+
+	server.Protocol = ProtocolRange{Min: features.ProtocolMin, Max: features.ProtocolMax}
+	server.ServerVersion = features.ServerVersion
+	server.Latency = latency
+	server.LastSeen = time.Now()
+	server.Score = score(server)
+
+	peers, err := client.ServerPeers(ctx)
+	if err != nil {
+		// the server is fine, it just does not share its peers
+		return server, nil, nil
+	}
+
+	candidates := make([]Server, 0, len(peers))
+	for _, peer := range peers {
+		candidate, err := c.peer(peer)
+		if err != nil {
+			continue
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return server, candidates, nil
+}
+
+// checkpoints verifies the block hashes at the checkpoints of the network.
+func (c *Crawler) checkpoints(ctx context.Context, client *electrum.Client) error {
+	for _, checkpoint := range c.network.Checkpoints {
+		header, err := client.GetBlockHeader(ctx, checkpoint.Height)
+		if err != nil {
+			return err
+		}
+
+		raw, err := hex.DecodeString(header.Header)
+		if err != nil {
+			return err
+		}
+
+		if hash := chainhash.DoubleHashH(raw); !strings.EqualFold(hash.String(), checkpoint.Hash) {
+			return fmt.Errorf("%w: block %d is %s", ErrWrongChain, checkpoint.Height, hash)
+		}
+	}
+
+	return nil
+}
+
+// peer converts an entry of a peer list into a server.
+func (c *Crawler) peer(peer *electrum.Peer) (Server, error) {
+	features, err := ParseFeatures(peer.Feats)
+	if err != nil {
+		return Server{}, err
+	}
+
+	host := peer.Host
+	if host == "" {
+		host = peer.Addr
+	}
+
+	server := Server{
+		Host:     host,
+		Protocol: ProtocolRange{Max: features.ProtocolMax},
+		Pruning:  features.Pruning,
+	}
+
+	if features.TCP {
+		server.TCPPort = portOr(features.TCPPort, c.network.DefaultTCPPort)
+	}
+
+	if features.SSL {
+		server.SSLPort = portOr(features.SSLPort, c.network.DefaultSSLPort)
+	}
+
+	if server.TCPPort == 0 && server.SSLPort == 0 {
+		return Server{}, fmt.Errorf("%w: %s", ErrNoPort, host)
+	}
+
+	return server, nil
+}
+
+// portOr returns port or def if port is not set.
+func portOr(port, def uint16) uint16 {
+	if port == 0 {
+		return def
+	}
+
+	return port
+}
+
+// score rates a server by its latency. Servers without SSL get half the score.
+func score(server Server) float64 {
+	s := 1 / (1 + float64(server.Latency)/float64(latencyScale))
+
+	if server.SSLPort == 0 {
+		s /= 2
+	}
+
+	return s
+}
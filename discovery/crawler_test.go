@@ -0,0 +1,204 @@
+package discovery_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/discovery"
+)
+
+// fakeServer is an Electrum server on a local port serving genesis, a block
+// header and peers.
+type fakeServer struct {
+	addr    string
+	port    uint16
+	genesis string
+	header  string
+	peers   [][]any
+}
+
+// testNetwork has a checkpoint at height 10 matching goodHeader.
+var (
+	goodHeader = strings.Repeat("01", 80)
+	forkHeader = strings.Repeat("02", 80)
+
+	testNetwork = discovery.Network{
+		Name:        "test",
+		GenesisHash: chaincfg.RegressionNetParams.GenesisHash.String(),
+		Checkpoints: []discovery.Checkpoint{{Height: 10, Hash: headerHash(goodHeader)}},
+	}
+)
+
+func headerHash(header string) string {
+	raw, _ := hex.DecodeString(header)
+	return chainhash.DoubleHashH(raw).String()
+}
+
+func newFakeServer(t *testing.T, genesis, header string) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeServer{
+		addr:    l.Addr().String(),
+		port:    uint16(l.Addr().(*net.TCPAddr).Port),
+		genesis: genesis,
+		header:  header,
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			return
+		}
+
+		var result any
+		switch req.Method {
+		case "server.version":
+			result = []string{"Fake 1.0", "1.4"}
+		case "server.features":
+			result = map[string]any{
+				"genesis_hash":   s.genesis,
+				"protocol_min":   "1.4",
+				"protocol_max":   "1.4.2",
+				"server_version": "Fake 1.0",
+				"hash_function":  "sha256",
+			}
+		case "blockchain.block.header":
+			result = s.header
+		case "server.peers.subscribe":
+			result = s.peers
+		}
+
+		resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		if _, err := conn.Write(append(resp, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) feature() string {
+	return "t" + strconv.Itoa(int(s.port))
+}
+
+func TestCrawl(t *testing.T) {
+	ctx := context.Background()
+
+	seed := newFakeServer(t, testNetwork.GenesisHash, goodHeader)
+	peer := newFakeServer(t, testNetwork.GenesisHash, goodHeader)
+	transitive := newFakeServer(t, testNetwork.GenesisHash, goodHeader)
+	liar := newFakeServer(t, discovery.Bitcoin.GenesisHash, goodHeader)
+	fork := newFakeServer(t, testNetwork.GenesisHash, forkHeader)
+
+	seed.peers = [][]any{
+		{"127.0.0.1", "127.0.0.1", []string{"v1.4", peer.feature()}},
+		{"127.0.0.1", "127.0.0.1", []string{"v1.4", liar.feature()}},
+		{"127.0.0.1", "127.0.0.1", []string{"v1.4", fork.feature()}},
+		{"abc.onion", "abc.onion", []string{"v1.4", "t"}},
+	}
+	peer.peers = [][]any{
+		{"127.0.0.1", "127.0.0.1", []string{"v1.4", transitive.feature()}},
+		{"127.0.0.1", "127.0.0.1", []string{"v1.4", seed.feature()}},
+	}
+
+	crawler := discovery.NewCrawler(testNetwork)
+
+	servers, err := crawler.Crawl(ctx, discovery.Server{Host: "127.0.0.1", TCPPort: seed.port})
+	require.NoError(t, err)
+
+	ports := make([]uint16, 0, len(servers))
+	for _, s := range servers {
+		ports = append(ports, s.TCPPort)
+
+		assert.Equal(t, discovery.ProtocolRange{Min: "1.4", Max: "1.4.2"}, s.Protocol)
+		assert.Positive(t, s.Score)
+		assert.False(t, s.LastSeen.IsZero())
+	}
+
+	assert.ElementsMatch(t, []uint16{seed.port, peer.port, transitive.port}, ports)
+
+	// the list survives a round trip through a file
+	path := filepath.Join(t.TempDir(), "servers.json")
+	require.NoError(t, discovery.Save(path, servers))
+
+	loaded, err := discovery.Load(path)
+	require.NoError(t, err)
+	require.Len(t, loaded, len(servers))
+	assert.Equal(t, servers[0].Host, loaded[0].Host)
+	assert.Equal(t, servers[0].TCPPort, loaded[0].TCPPort)
+	assert.Equal(t, servers[0].Score, loaded[0].Score)
+}
+
+func TestCrawl_BitcoinCashTestnet(t *testing.T) {
+	ctx := context.Background()
+
+	// shares the genesis block, but follows the Bitcoin Cash branch after the fork
+	bch := newFakeServer(t, discovery.Testnet.GenesisHash, forkHeader)
+
+	servers, err := discovery.NewCrawler(discovery.Testnet).Crawl(ctx, discovery.Server{Host: "127.0.0.1", TCPPort: bch.port})
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+}
+
+func TestSeeds(t *testing.T) {
+	networks := []discovery.Network{
+		discovery.Bitcoin,
+		discovery.Testnet,
+		discovery.BitcoinCash,
+		discovery.BitcoinCashTestnet,
+	}
+
+	for _, network := range networks {
+		servers, err := discovery.Seeds(network)
+		require.NoError(t, err)
+		require.NotEmpty(t, servers, network.Name)
+
+		for _, s := range servers {
+			assert.NotEmpty(t, s.Host)
+			assert.True(t, s.TCPPort != 0 || s.SSLPort != 0)
+		}
+	}
+
+	servers, err := discovery.Seeds(discovery.Network{Name: "unknown"})
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+}
@@ -0,0 +1,248 @@
+// Package discovery finds Electrum servers. It starts from a bundled seed list,
+// crawls the peer lists of the servers and verifies every candidate before it
+// ends up in the scored server list.
+package discovery
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+//go:embed servers/*.json
+var seeds embed.FS
+
+// Network describes the chain a server must serve.
+type Network struct {
+	Name           string
+	GenesisHash    string
+	DefaultTCPPort uint16
+	DefaultSSLPort uint16
+
+	// Checkpoints are blocks after forks sharing the genesis block, e.g. Bitcoin
+	// and Bitcoin Cash. A server must have all of them in its chain.
+	Checkpoints []Checkpoint
+}
+
+// Checkpoint is the hash of the block at a height.
+type Checkpoint struct {
+	Height uint32
+	Hash   string
+}
+
+var (
+	// Bitcoin is the Bitcoin main network.
+	Bitcoin = Network{
+		Name:           "bitcoin",
+		GenesisHash:    chaincfg.MainNetParams.GenesisHash.String(),
+		DefaultTCPPort: 50001,
+		DefaultSSLPort: 50002,
+		Checkpoints: []Checkpoint{
+			// first block after the Bitcoin Cash fork
+			{Height: 478559, Hash: "00000000000000000019f112ec0a9982926f1258cdcc558dd7c3b7e5dc7fa148"},
+		},
+	}
+
+	// Testnet is the Bitcoin test network version 3.
+	Testnet = Network{
+		Name:           "testnet",
+		GenesisHash:    chaincfg.TestNet3Params.GenesisHash.String(),
+		DefaultTCPPort: 51001,
+		DefaultSSLPort: 51002,
+		Checkpoints: []Checkpoint{
+			// checkpoint of btcd after the Bitcoin Cash fork at height 1155876
+			{Height: 1200007, Hash: "00000000000004f2dc41845771909db57e04191714ed8c963f7e56713a7b6cea"},
+		},
+	}
+
+	// BitcoinCash is the Bitcoin Cash main network.
+	BitcoinCash = Network{
+		Name:           "bitcoincash",
+		GenesisHash:    chaincfg.MainNetParams.GenesisHash.String(),
+		DefaultTCPPort: 50001,
+		DefaultSSLPort: 50002,
+		Checkpoints: []Checkpoint{
+			// first block after the fork from Bitcoin
+			{Height: 478559, Hash: "000000000000000000651ef99cb9fcbe0dadde1d424bd9f15ff20136191a5eec"},
+			// first block after the fork of Bitcoin SV
+			{Height: 556767, Hash: "0000000000000000004626ff6e3b936941d341c5932ece4357eeccac44e6d56c"},
+		},
+	}
+
+	// BitcoinCashTestnet is the Bitcoin Cash test network version 3.
+	BitcoinCashTestnet = Network{
+		Name:           "bitcoincash-testnet",
+		GenesisHash:    chaincfg.TestNet3Params.GenesisHash.String(),
+		DefaultTCPPort: 60001,
+		DefaultSSLPort: 60002,
+		Checkpoints: []Checkpoint{
+			// first block after the fork from Bitcoin
+			{Height: 1155876, Hash: "00000000000e38fef93ed9582a7df43815d5c2ba9fd37ef70c9a0ea4a285b8f5"},
+		},
+	}
+)
+
+// Server is an entry of a server list.
+type Server struct {
+	Host    string `json:"host"`
+	TCPPort uint16 `json:"tcp_port,omitempty"`
+	SSLPort uint16 `json:"ssl_port,omitempty"`
+
+	Protocol      ProtocolRange `json:"protocol"`
+	ServerVersion string        `json:"server_version,omitempty"`
+	Pruning       uint64        `json:"pruning,omitempty"`
+
+	// Score rates a verified server between 0 and 1, higher is better.
+	Score    float64       `json:"score"`
+	Latency  time.Duration `json:"latency,omitempty"`
+	LastSeen time.Time     `json:"last_seen,omitzero"`
+}
+
+// TCPAddr returns host:port of the TCP port or an empty string.
+func (s Server) TCPAddr() string {
+	if s.TCPPort == 0 {
+		return ""
+	}
+
+	return net.JoinHostPort(s.Host, strconv.Itoa(int(s.TCPPort)))
+}
+
+// SSLAddr returns host:port of the SSL port or an empty string.
+func (s Server) SSLAddr() string {
+	if s.SSLPort == 0 {
+		return ""
+	}
+
+	return net.JoinHostPort(s.Host, strconv.Itoa(int(s.SSLPort)))
+}
+
+// IsOnion reports whether the server is a Tor hidden service.
+func (s Server) IsOnion() bool {
+	return strings.HasSuffix(s.Host, ".onion")
+}
+
+// key identifies a server in the crawler.
+func (s Server) key() string {
+	return strings.ToLower(s.Host) + "|" + strconv.Itoa(int(s.TCPPort)) + "|" + strconv.Itoa(int(s.SSLPort))
+}
+
+// seedEntry is an entry of a servers.json file as used by Electrum.
+type seedEntry struct {
+	Pruning string `json:"pruning"`
+	SSL     string `json:"s"`
+	TCP     string `json:"t"`
+	Version string `json:"version"`
+}
+
+// Seeds returns the bundled seed servers of network.
+func Seeds(network Network) ([]Server, error) {
+	data, err := seeds.ReadFile("servers/" + network.Name + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ParseSeeds(data)
+}
+
+// ParseSeeds parses a server list in the servers.json format of Electrum.
+func ParseSeeds(data []byte) ([]Server, error) {
+	var entries map[string]seedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	servers := make([]Server, 0, len(entries))
+	for host, entry := range entries {
+		server := Server{
+			Host:     host,
+			Protocol: ProtocolRange{Max: entry.Version},
+		}
+
+		tcp, err := parsePort(entry.TCP)
+		if err != nil {
+			return nil, err
+		}
+
+		ssl, err := parsePort(entry.SSL)
+		if err != nil {
+			return nil, err
+		}
+
+		server.TCPPort, server.SSLPort = tcp, ssl
+
+		if entry.Pruning != "" && entry.Pruning != "-" {
+			if server.Pruning, err = strconv.ParseUint(entry.Pruning, 10, 64); err != nil {
+				return nil, err
+			}
+		}
+
+		servers = append(servers, server)
+	}
+
+	slices.SortFunc(servers, func(a, b Server) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+
+	return servers, nil
+}
+
+// Load reads a server list written by Save.
+func Load(path string) ([]Server, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read decodes a server list written by Write.
+func Read(r io.Reader) ([]Server, error) {
+	var servers []Server
+	if err := json.NewDecoder(r).Decode(&servers); err != nil {
+		return nil, err
+	}
+
+	return servers, nil
+}
+
+// Save writes a server list atomically to path.
+func Save(path string, servers []Server) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Write(tmp, servers); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Write encodes a server list as JSON.
+func Write(w io.Writer, servers []Server) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(servers)
+}
@@ -0,0 +1,116 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// ErrInvalidFeature throws an error if a peer feature can not be parsed.
+var ErrInvalidFeature = errors.New("invalid peer feature")
+
+// Features are the features a server announces in the server.peers.subscribe list,
+// e.g. ["v1.4", "s50002", "t50001"].
+type Features struct {
+	ProtocolMax string // protocol version from the "v" feature
+	TCP         bool   // TCP is offered
+	TCPPort     uint16 // 0 is the default port of the network
+	SSL         bool   // SSL is offered
+	SSLPort     uint16 // 0 is the default port of the network
+	Pruning     uint64 // pruning limit, 0 if the server is not pruning
+}
+
+// ParseFeatures parses the feature list of a peer. Unknown features are ignored.
+func ParseFeatures(feats []string) (Features, error) {
+	var f Features
+
+	for _, feat := range feats {
+		if feat == "" {
+			continue
+		}
+
+		value := feat[1:]
+
+		switch feat[0] {
+		case 'v':
+			if !validVersion(value) {
+				return Features{}, fmt.Errorf("%w: %s", ErrInvalidFeature, feat)
+			}
+
+			f.ProtocolMax = value
+		case 't':
+			port, err := parsePort(value)
+			if err != nil {
+				return Features{}, fmt.Errorf("%w: %s", ErrInvalidFeature, feat)
+			}
+
+			f.TCP, f.TCPPort = true, port
+		case 's':
+			port, err := parsePort(value)
+			if err != nil {
+				return Features{}, fmt.Errorf("%w: %s", ErrInvalidFeature, feat)
+			}
+
+			f.SSL, f.SSLPort = true, port
+		case 'p':
+			pruning, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return Features{}, fmt.Errorf("%w: %s", ErrInvalidFeature, feat)
+			}
+
+			f.Pruning = pruning
+		}
+	}
+
+	return f, nil
+}
+
+func parsePort(s string) (uint16, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("invalid port: %s", s)
+	}
+
+	return uint16(port), nil
+}
+
+// validVersion reports whether s is a dotted protocol version like 1.4.2.
+func validVersion(s string) bool {
+	for part := range strings.SplitSeq(s, ".") {
+		if n, err := strconv.Atoi(part); err != nil || n < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ProtocolRange is the range of protocol versions a server supports.
+type ProtocolRange struct {
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+}
+
+// Supports reports whether version is inside the range. An empty bound is not checked.
+func (r ProtocolRange) Supports(version string) bool {
+	if !validVersion(version) {
+		return false
+	}
+
+	if r.Min != "" && (!validVersion(r.Min) || electrum.CompareVersions(version, r.Min) < 0) {
+		return false
+	}
+
+	if r.Max != "" && (!validVersion(r.Max) || electrum.CompareVersions(version, r.Max) > 0) {
+		return false
+	}
+
+	return true
+}
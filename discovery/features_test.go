@@ -0,0 +1,43 @@
+package discovery_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/discovery"
+)
+
+func TestParseFeatures(t *testing.T) {
+	f, err := discovery.ParseFeatures([]string{"v1.4.2", "s50002", "t", "p10000", "x"})
+	require.NoError(t, err)
+
+	assert.Equal(t, discovery.Features{
+		ProtocolMax: "1.4.2",
+		TCP:         true,
+		SSL:         true,
+		SSLPort:     50002,
+		Pruning:     10000,
+	}, f)
+
+	_, err = discovery.ParseFeatures([]string{"s99999"})
+	assert.ErrorIs(t, err, discovery.ErrInvalidFeature)
+
+	_, err = discovery.ParseFeatures([]string{"vx"})
+	assert.ErrorIs(t, err, discovery.ErrInvalidFeature)
+}
+
+func TestProtocolRange(t *testing.T) {
+	r := discovery.ProtocolRange{Min: "1.4", Max: "1.5"}
+
+	assert.True(t, r.Supports("1.4"))
+	assert.True(t, r.Supports("1.4.2"))
+	assert.True(t, r.Supports("1.5.0"))
+	assert.False(t, r.Supports("1.3"))
+	assert.False(t, r.Supports("1.5.1"))
+	assert.False(t, r.Supports("bad"))
+	assert.False(t, r.Supports("1.-4"))
+	assert.True(t, r.Supports("1.4.10"))
+
+	assert.True(t, discovery.ProtocolRange{}.Supports("2.0"))
+}
@@ -3,20 +3,99 @@ package electrum
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 
-	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/btcsuite/btcd/btcutil/bech32"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 )
 
+var (
+	// ErrInvalidAddress throws an error if an address can't be decoded.
+	ErrInvalidAddress = errors.New("invalid address")
+
+	// ErrWrongNetwork throws an error if an address belongs to another network.
+	ErrWrongNetwork = errors.New("address belongs to another network")
+)
+
+// Network stores the address formats of a coin network.
+type Network struct {
+	Name string
+
+	// PubKeyHashAddrID and ScriptHashAddrID are the version bytes of base58 addresses.
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+
+	// Bech32HRPSegwit is the human-readable part of segwit addresses, it is empty
+	// if the network has no segwit.
+	Bech32HRPSegwit string
+
+	// CashAddrPrefix is the prefix of cashaddr addresses, it is empty if the network
+	// does not use cashaddr.
+	CashAddrPrefix string
+}
+
+// NewNetwork initialize a new network with the address formats of btcd chain parameters.
+func NewNetwork(params *chaincfg.Params) *Network {
+	return &Network{
+		Name:             params.Name,
+		PubKeyHashAddrID: params.PubKeyHashAddrID,
+		ScriptHashAddrID: params.ScriptHashAddrID,
+		Bech32HRPSegwit:  params.Bech32HRPSegwit,
+	}
+}
+
+// Networks supported out of the box.
+var (
+	BitcoinMainNet  = NewNetwork(&chaincfg.MainNetParams)
+	BitcoinTestNet3 = NewNetwork(&chaincfg.TestNet3Params)
+	BitcoinTestNet4 = NewNetwork(&chaincfg.TestNet4Params)
+	BitcoinSigNet   = NewNetwork(&chaincfg.SigNetParams)
+	BitcoinRegTest  = NewNetwork(&chaincfg.RegressionNetParams)
+
+	BitcoinCashMainNet = &Network{Name: "bch-mainnet", PubKeyHashAddrID: 0x00, ScriptHashAddrID: 0x05, CashAddrPrefix: "bitcoincash"}
+	BitcoinCashTestNet = &Network{Name: "bch-testnet", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0xc4, CashAddrPrefix: "bchtest"}
+	BitcoinCashRegTest = &Network{Name: "bch-regtest", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0xc4, CashAddrPrefix: "bchreg"}
+
+	LitecoinMainNet = &Network{Name: "ltc-mainnet", PubKeyHashAddrID: 0x30, ScriptHashAddrID: 0x32, Bech32HRPSegwit: "ltc"}
+	LitecoinTestNet = &Network{Name: "ltc-testnet", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0x3a, Bech32HRPSegwit: "tltc"}
+
+	DashMainNet = &Network{Name: "dash-mainnet", PubKeyHashAddrID: 0x4c, ScriptHashAddrID: 0x10}
+	DashTestNet = &Network{Name: "dash-testnet", PubKeyHashAddrID: 0x8c, ScriptHashAddrID: 0x13}
+)
+
+// WithNetwork sets the network of the addresses converted by the client, the
+// default is BitcoinMainNet.
+func WithNetwork(network *Network) ClientOption {
+	return func(c *clientConfig) {
+		c.network = network
+	}
+}
+
+// Network returns the network of the client.
+func (s *Client) Network() *Network {
+	return s.config.network
+}
+
+// AddressToElectrumScriptHash converts an address of the network of the client to
+// an electrum scripthash.
+func (s *Client) AddressToElectrumScriptHash(address string) (string, error) {
+	return AddressToElectrumScriptHash(address, s.config.network)
+}
+
 // AddressToElectrumScriptHash converts valid bitcoin address to electrum scriptHash sha256 encoded, reversed and encoded in hex
+// The address is decoded for network, the default is BitcoinMainNet.
 // https://electrumx.readthedocs.io/en/latest/protocol-basics.html#script-hashes
-func AddressToElectrumScriptHash(addressStr string) (string, error) {
-	address, err := btcutil.DecodeAddress(addressStr, &chaincfg.MainNetParams)
-	if err != nil {
-		return "", err
+func AddressToElectrumScriptHash(addressStr string, network ...*Network) (string, error) {
+	net := BitcoinMainNet
+	if len(network) > 0 && network[0] != nil {
+		net = network[0]
 	}
-	script, err := txscript.PayToAddrScript(address)
+
+	script, err := AddressToScript(addressStr, net)
 	if err != nil {
 		return "", err
 	}
@@ -29,3 +108,85 @@ func AddressToElectrumScriptHash(addressStr string) (string, error) {
 
 	return hex.EncodeToString(hashSum[:]), nil
 }
+
+// AddressToScript decodes a base58, segwit or cashaddr address of network and
+// returns its output script. It fails with ErrWrongNetwork if the address is
+// valid but belongs to another network.
+func AddressToScript(address string, network *Network) ([]byte, error) {
+	if strings.Contains(address, ":") {
+		return cashAddrScript(address, network)
+	}
+
+	if hash, version, err := base58.CheckDecode(address); err == nil && len(hash) == 20 {
+		switch version {
+		case network.PubKeyHashAddrID:
+			return p2pkhScript(hash)
+		case network.ScriptHashAddrID:
+			return p2shScript(hash)
+		}
+
+		return nil, fmt.Errorf("%w: %s is not a %s address", ErrWrongNetwork, address, network.Name)
+	}
+
+	if hrp, data, version, err := bech32.DecodeGeneric(address); err == nil {
+		if network.Bech32HRPSegwit == "" || !strings.EqualFold(hrp, network.Bech32HRPSegwit) {
+			return nil, fmt.Errorf("%w: %s is not a %s address", ErrWrongNetwork, address, network.Name)
+		}
+
+		return witnessScript(address, data, version)
+	}
+
+	if network.CashAddrPrefix != "" {
+		return cashAddrScript(address, network)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+}
+
+// witnessScript returns the output script of a decoded segwit address.
+func witnessScript(address string, data []byte, encoding bech32.Version) ([]byte, error) {
+	if len(data) < 1 || data[0] > 16 {
+		return nil, fmt.Errorf("%w: %s has no valid witness version", ErrInvalidAddress, address)
+	}
+
+	version := data[0]
+
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+
+	switch {
+	case len(program) < 2 || len(program) > 40:
+		return nil, fmt.Errorf("%w: %s has an invalid witness program length", ErrInvalidAddress, address)
+	case version == 0 && len(program) != 20 && len(program) != 32:
+		return nil, fmt.Errorf("%w: %s has an invalid witness program length", ErrInvalidAddress, address)
+	case version == 0 && encoding != bech32.Version0, version > 0 && encoding != bech32.VersionM:
+		return nil, fmt.Errorf("%w: %s has an invalid checksum encoding", ErrInvalidAddress, address)
+	}
+
+	op := byte(txscript.OP_0)
+	if version > 0 {
+		op = txscript.OP_1 + version - 1
+	}
+
+	return txscript.NewScriptBuilder().AddOp(op).AddData(program).Script()
+}
+
+func p2pkhScript(hash []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).AddData(hash).
+		AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// p2shScript returns the pay to script hash script, a 32 bytes hash is a P2SH32
+// script of Bitcoin Cash.
+func p2shScript(hash []byte) ([]byte, error) {
+	op := byte(txscript.OP_HASH160)
+	if len(hash) == 32 {
+		op = txscript.OP_HASH256
+	}
+
+	return txscript.NewScriptBuilder().AddOp(op).AddData(hash).AddOp(txscript.OP_EQUAL).Script()
+}
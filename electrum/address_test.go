@@ -1,10 +1,12 @@
 package electrum_test
 
 import (
+	"context"
+	"testing"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/zauberhaus/go-electrum/electrum"
-	"testing"
 )
 
 func TestAddressToElectrumScriptHash(t *testing.T) {
@@ -44,3 +46,77 @@ func TestAddressToElectrumScriptHash(t *testing.T) {
 		assert.Equal(t, tc.wantScriptHash, scriptHash)
 	}
 }
+
+func TestAddressToElectrumScriptHash_Networks(t *testing.T) {
+	const (
+		p2pkh  = "71b6a00546326a622c2a484e88a81909706a0cce15009aa87fd9a6569ca84c93"
+		p2sh   = "5a55e5e0a3b78433b0a3337817a83ef604b53dc73e792aa33f43fa7966cb76be"
+		p2wpkh = "f8d3a7f6141fb7c08d0fc5597dcf754093a908cafcbe4c17cedbbe91ff41f39c"
+	)
+
+	tests := []struct {
+		name           string
+		address        string
+		network        *electrum.Network
+		wantScriptHash string
+		wantErr        error
+	}{
+		{"default", "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", nil, p2pkh, nil},
+		{"testnet", "mrLC19Je2BuWQDkWSTriGYPyQJXKkkBmCx", electrum.BitcoinTestNet3, p2pkh, nil},
+		{"testnet4 segwit", "tb1qar0srrr7xfkvy5l643lydnw9re59gtzzy00gkn", electrum.BitcoinTestNet4, p2wpkh, nil},
+		{"signet segwit", "tb1qar0srrr7xfkvy5l643lydnw9re59gtzzy00gkn", electrum.BitcoinSigNet, p2wpkh, nil},
+		{"regtest segwit", "bcrt1qar0srrr7xfkvy5l643lydnw9re59gtzzxxk9p6", electrum.BitcoinRegTest, p2wpkh, nil},
+		{"cashaddr", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", electrum.BitcoinCashMainNet, p2pkh, nil},
+		{"cashaddr upper case", "BITCOINCASH:QPM2QSZNHKS23Z7629MMS6S4CWEF74VCWVY22GDX6A", electrum.BitcoinCashMainNet, p2pkh, nil},
+		{"cashaddr without prefix", "qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", electrum.BitcoinCashMainNet, p2pkh, nil},
+		{"cashaddr p2sh", "bitcoincash:ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq", electrum.BitcoinCashMainNet, p2sh, nil},
+		{"cashaddr token aware", "bitcoincash:zpm2qsznhks23z7629mms6s4cwef74vcwvrqekrq9w", electrum.BitcoinCashMainNet, p2pkh, nil},
+		{"cashaddr p2sh32", "bitcoincash:pvqqzqsrqszsvpcgpy9qkrqdpc83qygjzv2p29shrqv35xcur50p7h2c7ctj5", electrum.BitcoinCashMainNet,
+			"5fea5dea785d78052b0f2e05cb58177a1ab741f91710cf16c4a1e275226f23c4", nil},
+		{"cashaddr testnet", "bchtest:qpm2qsznhks23z7629mms6s4cwef74vcwvqcw003ap", electrum.BitcoinCashTestNet, p2pkh, nil},
+		{"bch legacy", "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", electrum.BitcoinCashMainNet, p2pkh, nil},
+		{"litecoin", "LW3ByJXVHpiJsuy3u2sdieFQkXHtuk93Yi", electrum.LitecoinMainNet, p2pkh, nil},
+		{"litecoin segwit", "ltc1qar0srrr7xfkvy5l643lydnw9re59gtzz24wl4s", electrum.LitecoinMainNet, p2wpkh, nil},
+		{"dash", "XmW5YLsZAsgqn3sUanCZJ9sSNeWJrFiohn", electrum.DashMainNet, p2pkh, nil},
+		{"dash testnet", "yX8gZHwzcRLv7no29dWxLBHnevzgPGamHq", electrum.DashTestNet, p2pkh, nil},
+
+		{"testnet on mainnet", "mrLC19Je2BuWQDkWSTriGYPyQJXKkkBmCx", nil, "", electrum.ErrWrongNetwork},
+		{"mainnet segwit on testnet", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", electrum.BitcoinTestNet3, "", electrum.ErrWrongNetwork},
+		{"litecoin on bitcoin", "LW3ByJXVHpiJsuy3u2sdieFQkXHtuk93Yi", electrum.BitcoinMainNet, "", electrum.ErrWrongNetwork},
+		{"segwit on dash", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", electrum.DashMainNet, "", electrum.ErrWrongNetwork},
+		{"cashaddr on bitcoin", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", electrum.BitcoinMainNet, "", electrum.ErrWrongNetwork},
+		{"cashaddr testnet on mainnet", "bchtest:qpm2qsznhks23z7629mms6s4cwef74vcwvqcw003ap", electrum.BitcoinCashMainNet, "", electrum.ErrWrongNetwork},
+		{"cashaddr checksum", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6b", electrum.BitcoinCashMainNet, "", electrum.ErrInvalidAddress},
+		{"cashaddr mixed case", "bitcoincash:Qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", electrum.BitcoinCashMainNet, "", electrum.ErrInvalidAddress},
+		{"invalid", "invalid-address", electrum.BitcoinCashMainNet, "", electrum.ErrInvalidAddress},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scriptHash, err := electrum.AddressToElectrumScriptHash(tc.address, tc.network)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantScriptHash, scriptHash)
+		})
+	}
+}
+
+func TestClient_AddressToElectrumScriptHash(t *testing.T) {
+	ctx := context.Background()
+
+	client := electrum.NewClient(ctx, NewMockTransport(), electrum.WithNetwork(electrum.LitecoinMainNet))
+	defer client.Shutdown()
+
+	assert.Equal(t, electrum.LitecoinMainNet, client.Network())
+
+	scriptHash, err := client.AddressToElectrumScriptHash("LW3ByJXVHpiJsuy3u2sdieFQkXHtuk93Yi")
+	require.NoError(t, err)
+	assert.Equal(t, "71b6a00546326a622c2a484e88a81909706a0cce15009aa87fd9a6569ca84c93", scriptHash)
+
+	_, err = client.AddressToElectrumScriptHash("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu")
+	assert.ErrorIs(t, err, electrum.ErrWrongNetwork)
+}
@@ -0,0 +1,343 @@
+package electrum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBatchPending throws an error if the result of a batch call is read before the batch was sent.
+var ErrBatchPending = errors.New("batch has not been sent")
+
+// batchCall is a single call of a batch request.
+type batchCall interface {
+	call() (method string, params []any)
+	resolve(result json.RawMessage, err error)
+	resolved() bool
+	failure() error
+}
+
+// BatchCall is the typed result of a single call in a batch request. It becomes
+// available when Batch.Send returns.
+type BatchCall[T any] struct {
+	method string
+	params []any
+
+	result T
+	err    error
+	done   bool
+}
+
+func (c *BatchCall[T]) call() (string, []any) {
+	return c.method, c.params
+}
+
+func (c *BatchCall[T]) resolve(result json.RawMessage, err error) {
+	c.done = true
+
+	if err != nil {
+		c.err = err
+		return
+	}
+
+	if len(result) == 0 {
+		result = json.RawMessage("null")
+	}
+
+	c.err = json.Unmarshal(result, &c.result)
+}
+
+func (c *BatchCall[T]) resolved() bool {
+	return c.done
+}
+
+func (c *BatchCall[T]) failure() error {
+	return c.err
+}
+
+// Result returns the result or the error of the call.
+func (c *BatchCall[T]) Result() (T, error) {
+	if !c.done {
+		var zero T
+		return zero, ErrBatchPending
+	}
+
+	return c.result, c.err
+}
+
+// Batch collects calls which are sent to the server as a single JSON-RPC batch request.
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	client *Client
+	calls  []batchCall
+}
+
+// NewBatch returns an empty batch request.
+func (s *Client) NewBatch() *Batch {
+	return &Batch{
+		client: s,
+	}
+}
+
+// BatchAdd adds a call of method to the batch. The result field of the response is
+// decoded into T.
+func BatchAdd[T any](b *Batch, method string, params ...any) *BatchCall[T] {
+	if params == nil {
+		params = []any{}
+	}
+
+	c := &BatchCall[T]{
+		method: method,
+		params: params,
+	}
+
+	b.calls = append(b.calls, c)
+	return c
+}
+
+// Len returns the number of calls waiting to be sent.
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Send sends all calls added since the last Send in one message through the batch
+// interceptors and waits for their responses. Errors returned by the server are
+// reported per call, the returned error is only set if the batch could not be
+// completed. In that case all unanswered calls fail with the same error.
+//
+// Servers limit the size of a message, so very large batches should be split.
+func (b *Batch) Send(ctx context.Context) error {
+	calls := b.calls
+	b.calls = nil
+
+	// calls of methods the negotiated protocol lacks fail without being sent
+	calls = slices.DeleteFunc(calls, func(c batchCall) bool {
+		method, _ := c.call()
+
+		if err := b.client.checkVersion(method); err != nil {
+			c.resolve(nil, err)
+			return true
+		}
+
+		return false
+	})
+
+	if len(calls) == 0 {
+		return nil
+	}
+
+	requests := make([]BatchRequest, len(calls))
+	for i, c := range calls {
+		requests[i].Method, requests[i].Params = c.call()
+	}
+
+	results, err := b.client.invokeBatch(ctx, requests)
+	if err == nil && len(results) != len(calls) {
+		err = fmt.Errorf("batch returned %d results for %d calls", len(results), len(calls))
+	}
+
+	for i, c := range calls {
+		if i < len(results) {
+			c.resolve(results[i].Result, results[i].Err)
+		} else {
+			c.resolve(nil, err)
+		}
+	}
+
+	return err
+}
+
+// callBatch is the innermost BatchInvoker, it reports the requests to the observer.
+func (s *Client) callBatch(ctx context.Context, requests []BatchRequest) ([]BatchResult, error) {
+	observer := s.config.observer
+	start := time.Now()
+
+	for _, r := range requests {
+		observer.RequestStarted(r.Method)
+	}
+
+	results := make([]BatchResult, len(requests))
+	answered := make([]bool, len(requests))
+
+	err := s.roundTripBatch(ctx, requests, func(i int, resp *container) {
+		answered[i] = true
+		results[i] = batchResult(resp)
+	})
+
+	duration := time.Since(start)
+	for i, r := range requests {
+		if !answered[i] {
+			results[i].Err = err
+		}
+
+		observer.RequestFinished(r.Method, duration, results[i].Err)
+	}
+
+	return results, err
+}
+
+// batchResult extracts the result of a response to a batch call.
+func batchResult(resp *container) BatchResult {
+	if resp.err != nil {
+		return BatchResult{Err: resp.err}
+	}
+
+	var r struct {
+		Result json.RawMessage `json:"result"`
+	}
+
+	if err := json.Unmarshal(resp.content, &r); err != nil {
+		return BatchResult{Err: err}
+	}
+
+	return BatchResult{Result: r.Result}
+}
+
+// roundTripBatch sends requests in one message and passes every response to
+// resolve. A batch timing out counts as a failed health check of the connection.
+func (s *Client) roundTripBatch(ctx context.Context, requests []BatchRequest, resolve func(i int, resp *container)) error {
+	select {
+	case <-s.quit:
+		return s.Err()
+	default:
+	}
+
+	if err := s.waitHandshake(ctx); err != nil {
+		return err
+	}
+
+	var cost float64
+	for _, r := range requests {
+		cost += s.config.methodCost(r.Method)
+	}
+
+	release, err := s.limiter.acquire(ctx, s.quit, cost)
+	if err != nil {
+		if s.IsShutdown() {
+			return s.Err()
+		}
+
+		return err
+	}
+
+	defer release()
+
+	ctx, cancel := withTimeout(ctx, "batch", s.config.batchTimeout(requests))
+	defer cancel()
+
+	generation := s.generation.Load()
+
+	msgs := make([]request, len(requests))
+	ids := make([]uint64, len(requests))
+	handlers := make([]chan *container, len(requests))
+
+	defer func() {
+		s.unregister(ids...)
+	}()
+
+	for i, r := range requests {
+		msgs[i] = request{
+			ID:     atomic.AddUint64(&s.nextID, 1),
+			Method: r.Method,
+			Params: r.Params,
+		}
+
+		handler, err := s.register(msgs[i].ID)
+		if err != nil {
+			return err
+		}
+
+		ids[i] = msgs[i].ID
+		handlers[i] = handler
+	}
+
+	bytes, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+
+	bytes = append(bytes, nl)
+
+	err = s.send(ctx, bytes)
+	if err != nil {
+		if errors.Is(err, ErrTimeout) {
+			s.failed(generation, err)
+		}
+
+		return err
+	}
+
+	var throttled error
+
+	for i, handler := range handlers {
+		select {
+		case resp := <-handler:
+			s.failures.Store(0)
+
+			resp = s.limitResponse(msgs[i].Method, resp)
+			if IsThrottled(resp.err) {
+				throttled = resp.err
+			}
+
+			resolve(i, resp)
+		case <-s.quit:
+			return s.Err()
+		case <-ctx.Done():
+			err := contextErr(ctx)
+			if errors.Is(err, ErrTimeout) {
+				s.failed(generation, err)
+			}
+
+			return err
+		}
+	}
+
+	// the calls keep the error of the server, see IsThrottled
+	s.checkThrottled("batch", throttled)
+
+	return nil
+}
+
+// GetBalance adds a GetBalance call to the batch.
+func (b *Batch) GetBalance(scripthash string) *BatchCall[GetBalanceResult] {
+	return BatchAdd[GetBalanceResult](b, "blockchain.scripthash.get_balance", scripthash)
+}
+
+// GetHistory adds a GetHistory call to the batch.
+func (b *Batch) GetHistory(scripthash string) *BatchCall[[]*GetMempoolResult] {
+	return BatchAdd[[]*GetMempoolResult](b, "blockchain.scripthash.get_history", scripthash)
+}
+
+// GetMempool adds a GetMempool call to the batch.
+func (b *Batch) GetMempool(scripthash string) *BatchCall[[]*GetMempoolResult] {
+	return BatchAdd[[]*GetMempoolResult](b, "blockchain.scripthash.get_mempool", scripthash)
+}
+
+// ListUnspent adds a ListUnspent call to the batch.
+func (b *Batch) ListUnspent(scripthash string) *BatchCall[[]*ListUnspentResult] {
+	return BatchAdd[[]*ListUnspentResult](b, "blockchain.scripthash.listunspent", scripthash)
+}
+
+// GetTransaction adds a GetTransaction call to the batch.
+func (b *Batch) GetTransaction(txHash string) *BatchCall[*GetTransactionResult] {
+	return BatchAdd[*GetTransactionResult](b, "blockchain.transaction.get", txHash, true)
+}
+
+// GetRawTransaction adds a GetRawTransaction call to the batch.
+func (b *Batch) GetRawTransaction(txHash string) *BatchCall[string] {
+	return BatchAdd[string](b, "blockchain.transaction.get", txHash, false)
+}
+
+// GetMerkleProof adds a GetMerkleProof call to the batch.
+func (b *Batch) GetMerkleProof(txHash string, height uint32) *BatchCall[*GetMerkleProofResult] {
+	return BatchAdd[*GetMerkleProofResult](b, "blockchain.transaction.get_merkle", txHash, height)
+}
+
+// GetFee adds a GetFee call to the batch.
+func (b *Batch) GetFee(target uint32) *BatchCall[float32] {
+	return BatchAdd[float32](b, "blockchain.estimatefee", target)
+}
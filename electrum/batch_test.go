@@ -0,0 +1,100 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	client, transport := newTestClient(ctx, t)
+	defer client.Shutdown()
+
+	b := client.NewBatch()
+	balance := b.GetBalance("sh1")
+	unspent := b.ListUnspent("sh2")
+	failed := b.GetRawTransaction("tx")
+
+	_, err := balance.Result()
+	assert.ErrorIs(t, err, electrum.ErrBatchPending)
+
+	go func() {
+		var reqs []struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+
+		if err := json.Unmarshal(<-transport.sent(), &reqs); err != nil {
+			panic(err)
+		}
+
+		var resps []any
+		for _, req := range reqs {
+			switch req.Method {
+			case "blockchain.scripthash.get_balance":
+				resps = append(resps, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": map[string]any{"confirmed": 1.5, "unconfirmed": 0.5}})
+			case "blockchain.scripthash.listunspent":
+				resps = append(resps, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": []any{map[string]any{"height": 10, "tx_pos": 1, "tx_hash": "abc", "value": 1000}}})
+			default:
+				resps = append(resps, map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": map[string]any{"code": 2, "message": "unknown transaction"}})
+			}
+		}
+
+		// servers may answer in any order
+		slices.Reverse(resps)
+
+		data, _ := json.Marshal(resps)
+		transport.responses() <- data
+	}()
+
+	require.NoError(t, b.Send(ctx))
+	assert.Equal(t, 0, b.Len())
+
+	bal, err := balance.Result()
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 1.5, Unconfirmed: 0.5}, bal)
+
+	utxos, err := unspent.Result()
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	assert.Equal(t, &electrum.ListUnspentResult{Height: 10, Position: 1, Hash: "abc", Value: 1000}, utxos[0])
+
+	_, err = failed.Result()
+	var apiErr *electrum.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 2, apiErr.Code)
+}
+
+func TestBatch_Empty(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(ctx, t)
+	defer client.Shutdown()
+
+	assert.NoError(t, client.NewBatch().Send(ctx))
+}
+
+func TestBatch_Shutdown(t *testing.T) {
+	ctx := context.Background()
+	client, transport := newTestClient(ctx, t)
+
+	b := client.NewBatch()
+	call := electrum.BatchAdd[string](b, "server.banner")
+
+	go func() {
+		<-transport.sent()
+		client.Shutdown()
+	}()
+
+	err := b.Send(ctx)
+	assert.ErrorIs(t, err, electrum.ErrServerShutdown)
+
+	_, err = call.Result()
+	assert.ErrorIs(t, err, electrum.ErrServerShutdown)
+}
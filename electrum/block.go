@@ -2,7 +2,9 @@ package electrum
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 )
 
 var (
@@ -65,6 +67,28 @@ type GetBlockHeadersResult struct {
 	Root    string   `json:"root,omitempty"`
 }
 
+// UnmarshalJSON accepts the concatenated "hex" of protocol 1.4 and the "headers"
+// list of protocol 1.6, which is concatenated into Headers.
+func (r *GetBlockHeadersResult) UnmarshalJSON(data []byte) error {
+	type alias GetBlockHeadersResult
+
+	var v struct {
+		alias
+		List []string `json:"headers"`
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*r = GetBlockHeadersResult(v.alias)
+	if r.Headers == "" {
+		r.Headers = strings.Join(v.List, "")
+	}
+
+	return nil
+}
+
 // GetBlockHeaders return a concatenated chunk of block headers.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-block-headers
 func (s *Client) GetBlockHeaders(ctx context.Context, startHeight, count uint32,
@@ -0,0 +1,137 @@
+package electrum
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// WithNotificationHandler sets a handler for the notifications no subscription is
+// listening for, e.g. of methods the client does not wrap. Without a handler such
+// notifications are logged and dropped.
+func WithNotificationHandler(handler NotificationHandler) ClientOption {
+	return func(c *clientConfig) {
+		c.notificationHandler = handler
+	}
+}
+
+// Call sends a request for any method, including those the client does not wrap,
+// and decodes the result of the response into result. A nil result discards it,
+// a *json.RawMessage keeps it undecoded.
+func (s *Client) Call(ctx context.Context, method string, params []any, result any) error {
+	if params == nil {
+		params = []any{}
+	}
+
+	if err := s.checkVersion(method); err != nil {
+		return err
+	}
+
+	raw, err := s.invoke(ctx, method, params)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	}
+
+	return json.Unmarshal(raw, result)
+}
+
+// Subscribe sends the subscription request method with params and returns its
+// result and a channel receiving the params of the notifications pushed for
+// method. All notifications of method are delivered, even those belonging to
+// other subscriptions of the same method. A reconnecting client renews the
+// subscription after the connection has been restored and sends the result of the
+// renewal to the channel as well. The subscription ends
+// and the channel is closed when ctx is done or the client shuts down.
+func (s *Client) Subscribe(ctx context.Context, method string, params []any) (json.RawMessage, <-chan json.RawMessage, error) {
+	// Listen before subscribing, the first notification may directly follow the response.
+	ch := s.listenPush(method)
+
+	var result json.RawMessage
+	err := s.Call(ctx, method, params, &result)
+	if err != nil {
+		s.unlistenPush(method, ch)
+		return nil, nil, err
+	}
+
+	notifChan := make(chan json.RawMessage, 10)
+
+	var lock sync.Mutex
+	var closed bool
+
+	id := s.addSubscription(func(ctx context.Context) error {
+		var result json.RawMessage
+		if err := s.Call(ctx, method, params, &result); err != nil {
+			return err
+		}
+
+		// the state may have changed while the connection was lost
+		lock.Lock()
+		defer lock.Unlock()
+
+		if !closed {
+			offer(notifChan, result)
+		}
+
+		return nil
+	})
+
+	go func() {
+		defer func() {
+			lock.Lock()
+			defer lock.Unlock()
+
+			closed = true
+			close(notifChan)
+		}()
+
+		defer func() {
+			s.removeSubscription(id)
+			s.unlistenPush(method, ch)
+
+			// deliver may still hold the handler, make room for its last message
+			select {
+			case <-ch:
+			default:
+			}
+		}()
+
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok || msg.err != nil {
+					return
+				}
+
+				var notif response
+
+				err := json.Unmarshal(msg.content, &notif)
+				if err != nil {
+					s.log.Warnf("Unmarshaling of %s notification failed: %v", method, err)
+					continue
+				}
+
+				select {
+				case notifChan <- notif.Params:
+				case <-s.quit:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return result, notifChan, nil
+}
@@ -0,0 +1,187 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestClient_Call(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake())
+	defer client.Shutdown()
+
+	go func() {
+		msg := <-transport.sent()
+
+		var req struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(msg, &req)
+
+		resp, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  map[string]any{"method": req.Method, "params": req.Params},
+		})
+		transport.responses() <- resp
+	}()
+
+	var result struct {
+		Method string `json:"method"`
+		Params []any  `json:"params"`
+	}
+
+	err := client.Call(ctx, "daemon.passthrough", []any{"getinfo", 1}, &result)
+	require.NoError(t, err)
+	assert.Equal(t, "daemon.passthrough", result.Method)
+	assert.Equal(t, []any{"getinfo", float64(1)}, result.Params)
+
+	stop := startAutoResponder(transport, []int{1, 2})
+	defer stop()
+
+	var raw json.RawMessage
+	require.NoError(t, client.Call(ctx, "blockchain.outpoint.subscribe", nil, &raw))
+	assert.JSONEq(t, `[1,2]`, string(raw))
+}
+
+func TestClient_CallError(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake())
+	defer client.Shutdown()
+
+	go func() {
+		msg := <-transport.sent()
+
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		json.Unmarshal(msg, &req)
+
+		resp, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"error":   map[string]any{"code": -32601, "message": "unknown method"},
+		})
+		transport.responses() <- resp
+	}()
+
+	err := client.Call(ctx, "unknown.method", nil, nil)
+
+	var apiErr *electrum.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, -32601, apiErr.Code)
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	ctx := context.Background()
+
+	unknown := make(chan string, 10)
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake(), electrum.WithNotificationHandler(func(method string, params json.RawMessage) {
+		unknown <- method + " " + string(params)
+	}))
+	defer client.Shutdown()
+
+	stop := startAutoResponder(transport, "status")
+	defer stop()
+
+	sub, cancel := context.WithCancel(ctx)
+	result, notifications, err := client.Subscribe(sub, "blockchain.outpoint.subscribe", []any{"aa", 0})
+	require.NoError(t, err)
+	assert.JSONEq(t, `"status"`, string(result))
+
+	require.NoError(t, transport.notify("blockchain.outpoint.subscribe", []any{[]any{"aa", 0}, map[string]any{"height": 1}}))
+
+	select {
+	case params := <-notifications:
+		assert.JSONEq(t, `[["aa",0],{"height":1}]`, string(params))
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no notification received")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-notifications:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "channel not closed")
+	}
+
+	assert.Zero(t, client.Stats().Subscriptions)
+
+	// without a subscription the notification goes to the handler of the client
+	require.NoError(t, transport.notify("blockchain.outpoint.subscribe", []any{"bb"}))
+
+	select {
+	case msg := <-unknown:
+		assert.Equal(t, `blockchain.outpoint.subscribe ["bb"]`, msg)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no notification handled")
+	}
+}
+
+func TestClient_SubscribeRenewal(t *testing.T) {
+	ctx := context.Background()
+
+	first := NewMockTransport()
+	second := NewMockTransport()
+
+	client := electrum.NewClient(ctx, first, electrum.WithoutHandshake(), electrum.WithReconnect(dialSequence(second)), electrum.WithBackoff(testBackoff))
+	defer client.Shutdown()
+
+	var notifications <-chan json.RawMessage
+	err := first.exec(1, "status1", func() error {
+		_, ch, err := client.Subscribe(ctx, "blockchain.outpoint.subscribe", []any{"aa", 0})
+		notifications = ch
+		return err
+	})
+	require.NoError(t, err)
+
+	first.fail(io.EOF)
+
+	results := map[string]any{
+		"server.version":                [2]string{"ElectrumX 1.16.0", "1.4"},
+		"blockchain.outpoint.subscribe": "status2",
+	}
+
+	for range results {
+		respond(t, second, func(method string) any {
+			return results[method]
+		})
+	}
+
+	// the status changed during the outage
+	select {
+	case result := <-notifications:
+		assert.JSONEq(t, `"status2"`, string(result))
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "renewal result not delivered")
+	}
+}
+
+func TestClient_SubscribeError(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport)
+	client.Shutdown()
+
+	_, _, err := client.Subscribe(ctx, "blockchain.outpoint.subscribe", []any{"aa", 0})
+	assert.ErrorIs(t, err, electrum.ErrServerShutdown)
+	assert.Zero(t, client.Stats().Subscriptions)
+}
@@ -0,0 +1,98 @@
+package electrum
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+const cashAddrCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// cashAddrHashSizes maps the size bits of the version byte to the hash length.
+var cashAddrHashSizes = [8]int{20, 24, 28, 32, 40, 48, 56, 64}
+
+// cashAddrScript decodes a cashaddr address of network and returns its output script.
+// The prefix may be omitted.
+// https://reference.cash/protocol/blockchain/encoding/cashaddr
+func cashAddrScript(address string, network *Network) ([]byte, error) {
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return nil, fmt.Errorf("%w: %s has mixed case", ErrInvalidAddress, address)
+	}
+
+	prefix, payload, found := strings.Cut(strings.ToLower(address), ":")
+	if !found {
+		prefix, payload = network.CashAddrPrefix, prefix
+	}
+
+	values := make([]byte, 0, len(prefix)+1+len(payload))
+	for _, c := range []byte(prefix) {
+		values = append(values, c&0x1f)
+	}
+
+	values = append(values, 0)
+
+	for _, c := range []byte(payload) {
+		i := strings.IndexByte(cashAddrCharset, c)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s has an invalid character %q", ErrInvalidAddress, address, c)
+		}
+
+		values = append(values, byte(i))
+	}
+
+	if len(payload) <= 8 || cashAddrPolymod(values) != 0 {
+		return nil, fmt.Errorf("%w: %s has an invalid checksum", ErrInvalidAddress, address)
+	}
+
+	if prefix != network.CashAddrPrefix {
+		return nil, fmt.Errorf("%w: %s is not a %s address", ErrWrongNetwork, address, network.Name)
+	}
+
+	data, err := bech32.ConvertBits(values[len(prefix)+1:len(values)-8], 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: %s has no payload", ErrInvalidAddress, address)
+	}
+
+	version, hash := data[0], data[1:]
+	if version&0x80 != 0 || len(hash) != cashAddrHashSizes[version&0x07] {
+		return nil, fmt.Errorf("%w: %s has an invalid hash length", ErrInvalidAddress, address)
+	}
+
+	// types 2 and 3 are the token aware variants of 0 and 1
+	switch version >> 3 {
+	case 0, 2:
+		if len(hash) == 20 {
+			return p2pkhScript(hash)
+		}
+	case 1, 3:
+		if len(hash) == 20 || len(hash) == 32 {
+			return p2shScript(hash)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s has an unsupported type", ErrInvalidAddress, address)
+}
+
+// cashAddrPolymod computes the checksum of the cashaddr values, it is zero for a
+// valid address.
+func cashAddrPolymod(values []byte) uint64 {
+	c := uint64(1)
+
+	for _, d := range values {
+		c0 := byte(c >> 35)
+		c = ((c & 0x07ffffffff) << 5) ^ uint64(d)
+
+		for i, g := range []uint64{0x98f2bc8e61, 0x79b76d99e2, 0xf33e5fb3c4, 0xae2eabe2a8, 0x1e4f43e470} {
+			if c0&(1<<i) != 0 {
+				c ^= g
+			}
+		}
+	}
+
+	return c ^ 1
+}
@@ -0,0 +1,528 @@
+package electrum
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/zauberhaus/logger"
+)
+
+// ErrNotRecorded throws an error if a replayed client sends a request the cassette
+// has no response for.
+var ErrNotRecorded = errors.New("request not recorded")
+
+// NotRecordedError is returned by a ReplayTransport for a request the cassette has
+// no response for. Only this request fails, the client keeps running.
+type NotRecordedError struct {
+	ID     uint64          // id of the request
+	Method string          // method of the request
+	Params json.RawMessage // params of the request
+}
+
+func (e *NotRecordedError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrNotRecorded, e.Method, e.Params)
+}
+
+func (e *NotRecordedError) Unwrap() error {
+	return ErrNotRecorded
+}
+
+func (e *NotRecordedError) requestID() uint64 {
+	return e.ID
+}
+
+// Interaction is a line of a cassette. It is either a request with its response or
+// a notification pushed by the server.
+type Interaction struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+
+	// Notification marks a message pushed by the server. It is replayed after the
+	// response recorded before it.
+	Notification bool `json:"notification,omitempty"`
+}
+
+// matches reports whether the interaction answers a request for method with params.
+func (i *Interaction) matches(method string, params json.RawMessage) bool {
+	return !i.Notification && i.Method == method && bytes.Equal(i.Params, params)
+}
+
+// message encodes the interaction as the JSON-RPC message a server would send.
+func (i *Interaction) message(id uint64) ([]byte, error) {
+	msg := map[string]any{"jsonrpc": "2.0"}
+
+	if i.Notification {
+		msg["method"] = i.Method
+		if i.Params != nil {
+			msg["params"] = i.Params
+		}
+
+		return json.Marshal(msg)
+	}
+
+	msg["id"] = id
+	if i.Error != nil {
+		msg["error"] = i.Error
+	} else if i.Result != nil {
+		msg["result"] = i.Result
+	} else {
+		msg["result"] = nil
+	}
+
+	return json.Marshal(msg)
+}
+
+// LoadCassette reads the cassette written to path by a RecordingTransport.
+func LoadCassette(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadCassette(f)
+}
+
+// ReadCassette decodes a cassette, one interaction per line.
+func ReadCassette(r io.Reader) ([]Interaction, error) {
+	var interactions []Interaction
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, DefaultMaxMessageSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var interaction Interaction
+		if err := json.Unmarshal(data, &interaction); err != nil {
+			return nil, fmt.Errorf("cassette line %d: %w", line, err)
+		}
+
+		params, err := canonical(interaction.Params)
+		if err != nil {
+			return nil, fmt.Errorf("cassette line %d: %w", line, err)
+		}
+		interaction.Params = params
+
+		interactions = append(interactions, interaction)
+	}
+
+	return interactions, scanner.Err()
+}
+
+// canonical re-encodes JSON with sorted keys and without whitespace, so params
+// can be compared byte by byte.
+func canonical(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	if params, ok := v.([]any); ok && len(params) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
+
+// rpcMessage is a request, response or notification seen by the cassette transports.
+type rpcMessage struct {
+	ID     *uint64         `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// splitMessages decodes a single message or a batch.
+func splitMessages(data []byte) ([]rpcMessage, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var msgs []rpcMessage
+		if err := json.Unmarshal(trimmed, &msgs); err != nil {
+			return nil, err
+		}
+
+		return msgs, nil
+	}
+
+	var msg rpcMessage
+	if err := json.Unmarshal(trimmed, &msg); err != nil {
+		return nil, err
+	}
+
+	return []rpcMessage{msg}, nil
+}
+
+// RecordingTransport store information about a transport writing every exchange
+// with the wrapped transport to a cassette.
+type RecordingTransport struct {
+	transport Transport
+	responses chan []byte
+
+	lock    sync.Mutex
+	enc     *json.Encoder
+	pending map[uint64]Interaction
+	err     error
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	log logger.Logger
+}
+
+// NewRecordingTransport initialize a new transport recording the traffic of
+// transport to w. Responses and notifications are written in the order they
+// arrive.
+func NewRecordingTransport(ctx context.Context, transport Transport, w io.Writer) *RecordingTransport {
+	t := &RecordingTransport{
+		transport: transport,
+		responses: make(chan []byte),
+		enc:       json.NewEncoder(w),
+		pending:   make(map[uint64]Interaction),
+		done:      make(chan struct{}),
+		log:       logger.GetLogger(ctx),
+	}
+
+	go t.listen()
+
+	return t
+}
+
+func (t *RecordingTransport) listen() {
+	for {
+		select {
+		case <-t.done:
+			return
+		case msg := <-t.transport.Responses():
+			t.record(msg)
+
+			select {
+			case t.responses <- msg:
+			case <-t.done:
+				return
+			}
+		}
+	}
+}
+
+// record writes the responses and notifications of a received message.
+func (t *RecordingTransport) record(data []byte) {
+	msgs, err := splitMessages(data)
+	if err != nil {
+		t.log.Warnf("Record message failed: %v", err)
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, msg := range msgs {
+		if msg.ID == nil {
+			params, err := canonical(msg.Params)
+			if err != nil {
+				t.log.Warnf("Record notification failed: %v", err)
+				continue
+			}
+
+			t.write(Interaction{Method: msg.Method, Params: params, Notification: true})
+			continue
+		}
+
+		interaction, ok := t.pending[*msg.ID]
+		if !ok {
+			t.log.Warnf("Record response failed: unknown id %d", *msg.ID)
+			continue
+		}
+		delete(t.pending, *msg.ID)
+
+		interaction.Result = msg.Result
+		interaction.Error = msg.Error
+		t.write(interaction)
+	}
+}
+
+// write appends an interaction to the cassette. The first failure is kept and
+// returned by Close.
+func (t *RecordingTransport) write(interaction Interaction) {
+	if t.err != nil {
+		return
+	}
+
+	if err := t.enc.Encode(interaction); err != nil {
+		t.log.Errorf("Write cassette failed: %v", err)
+		t.err = err
+	}
+}
+
+// SendMessage remembers the requests in body and sends it through the wrapped transport.
+func (t *RecordingTransport) SendMessage(body []byte) error {
+	return t.SendMessageContext(context.Background(), body)
+}
+
+// SendMessageContext remembers the requests in body and sends it through the wrapped
+// transport, which may cancel the write with ctx if it supports it.
+func (t *RecordingTransport) SendMessageContext(ctx context.Context, body []byte) error {
+	msgs, err := splitMessages(body)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	for _, msg := range msgs {
+		if msg.ID == nil {
+			continue
+		}
+
+		params, err := canonical(msg.Params)
+		if err != nil {
+			t.lock.Unlock()
+			return err
+		}
+
+		t.pending[*msg.ID] = Interaction{Method: msg.Method, Params: params}
+	}
+	t.lock.Unlock()
+
+	if sender, ok := t.transport.(ContextSender); ok {
+		return sender.SendMessageContext(ctx, body)
+	}
+
+	return t.transport.SendMessage(body)
+}
+
+// Responses returns chan to transport responses.
+func (t *RecordingTransport) Responses() <-chan []byte {
+	return t.responses
+}
+
+// Errors returns chan to transport errors.
+func (t *RecordingTransport) Errors() <-chan error {
+	return t.transport.Errors()
+}
+
+// Close closes the wrapped transport and returns the first error writing the cassette.
+func (t *RecordingTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+
+	err := t.transport.Close()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.err != nil {
+		return t.err
+	}
+
+	return err
+}
+
+// ReplayTransport store information about a transport answering requests from a
+// cassette instead of a server.
+type ReplayTransport struct {
+	interactions []Interaction
+	used         []bool
+
+	responses chan []byte
+	errors    chan error
+
+	lock   sync.Mutex
+	queue  []replay
+	queued chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	log logger.Logger
+}
+
+// replay is a message or a request error queued by a ReplayTransport.
+type replay struct {
+	msg []byte
+	err error
+}
+
+// NewReplayTransport initialize a new transport serving interactions. Every request
+// gets the first unused response recorded for the same method and params, followed
+// by the notifications recorded after that response. Once all matching responses
+// have been used the last one is repeated, e.g. for keep alive pings. Requests
+// without a recorded response fail with ErrNotRecorded.
+func NewReplayTransport(ctx context.Context, interactions []Interaction) *ReplayTransport {
+	t := &ReplayTransport{
+		interactions: interactions,
+		used:         make([]bool, len(interactions)),
+		responses:    make(chan []byte),
+		errors:       make(chan error),
+		queued:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		log:          logger.GetLogger(ctx),
+	}
+
+	// notifications recorded before the first response, e.g. after a reconnect
+	t.lock.Lock()
+	t.notifications(0)
+	t.lock.Unlock()
+
+	go t.deliver()
+
+	return t
+}
+
+func (t *ReplayTransport) deliver() {
+	for {
+		t.lock.Lock()
+		if len(t.queue) == 0 {
+			t.lock.Unlock()
+
+			select {
+			case <-t.queued:
+				continue
+			case <-t.done:
+				return
+			}
+		}
+
+		next := t.queue[0]
+		t.queue = t.queue[1:]
+		t.lock.Unlock()
+
+		// only one of the channels is set, a nil channel blocks
+		responses, errs := t.responses, t.errors
+		if next.err != nil {
+			responses = nil
+		} else {
+			errs = nil
+		}
+
+		select {
+		case responses <- next.msg:
+		case errs <- next.err:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// SendMessage answers the requests in body from the cassette.
+func (t *ReplayTransport) SendMessage(body []byte) error {
+	msgs, err := splitMessages(body)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, msg := range msgs {
+		if msg.ID == nil {
+			continue
+		}
+
+		params, err := canonical(msg.Params)
+		if err != nil {
+			return err
+		}
+
+		index := t.find(msg.Method, params)
+		if index < 0 {
+			err := &NotRecordedError{ID: *msg.ID, Method: msg.Method, Params: params}
+
+			t.log.Debugf("replay -> %v", err)
+			t.queue = append(t.queue, replay{err: err})
+			continue
+		}
+
+		reply, err := t.interactions[index].message(*msg.ID)
+		if err != nil {
+			return err
+		}
+
+		t.log.Debugf("replay -> %s", reply)
+		t.queue = append(t.queue, replay{msg: reply})
+
+		if !t.used[index] {
+			t.used[index] = true
+			t.notifications(index + 1)
+		}
+	}
+
+	select {
+	case t.queued <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// find returns the index of the response for method and params or -1.
+func (t *ReplayTransport) find(method string, params json.RawMessage) int {
+	last := -1
+
+	for i := range t.interactions {
+		if !t.interactions[i].matches(method, params) {
+			continue
+		}
+
+		if !t.used[i] {
+			return i
+		}
+
+		last = i
+	}
+
+	return last
+}
+
+// notifications queues the notifications recorded from index on up to the next response.
+func (t *ReplayTransport) notifications(index int) {
+	for ; index < len(t.interactions) && t.interactions[index].Notification; index++ {
+		msg, err := t.interactions[index].message(0)
+		if err != nil {
+			t.log.Warnf("Replay notification failed: %v", err)
+			continue
+		}
+
+		t.queue = append(t.queue, replay{msg: msg})
+	}
+}
+
+// Responses returns chan to transport responses.
+func (t *ReplayTransport) Responses() <-chan []byte {
+	return t.responses
+}
+
+// Errors returns chan to transport errors.
+func (t *ReplayTransport) Errors() <-chan error {
+	return t.errors
+}
+
+// Close stops the replay.
+func (t *ReplayTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+
+	return nil
+}
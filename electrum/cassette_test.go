@@ -0,0 +1,201 @@
+package electrum_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// serveHeaders answers like serveLines and pushes a new header right after the
+// response to a header subscription.
+func serveHeaders(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			return
+		}
+
+		var result any = req.Method
+		switch req.Method {
+		case "server.version":
+			result = []string{"Fake 1.0", electrum.ProtocolMax}
+		case "blockchain.headers.subscribe":
+			result = map[string]any{"height": 100, "hex": "00"}
+		case "blockchain.scripthash.get_balance":
+			result = map[string]any{"confirmed": 1000, "unconfirmed": 10}
+		}
+
+		resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		if _, err := conn.Write(append(resp, '\n')); err != nil {
+			return
+		}
+
+		if req.Method == "blockchain.headers.subscribe" {
+			notif, _ := json.Marshal(map[string]any{
+				"jsonrpc": "2.0",
+				"method":  "blockchain.headers.subscribe",
+				"params":  []any{map[string]any{"height": 101, "hex": "01"}},
+			})
+			if _, err := conn.Write(append(notif, '\n')); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// exercise runs the calls recorded and replayed by TestCassette.
+func exercise(ctx context.Context, t *testing.T, client *electrum.Client) {
+	t.Helper()
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(100), (<-headers).Height)
+	assert.Equal(t, int32(101), (<-headers).Height)
+
+	balance, err := client.GetBalance(ctx, "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161")
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 1000, Unconfirmed: 10}, balance)
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "server.banner", banner)
+}
+
+func TestCassette(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, server := net.Pipe()
+	go serveHeaders(server)
+
+	var cassette bytes.Buffer
+
+	recorder := electrum.NewRecordingTransport(ctx, electrum.NewStreamTransport(ctx, conn), &cassette)
+	client := electrum.NewClient(ctx, recorder, electrum.WithoutHandshake())
+	exercise(ctx, t, client)
+	client.Shutdown()
+
+	interactions, err := electrum.ReadCassette(bytes.NewReader(cassette.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, interactions, 4)
+
+	assert.Equal(t, "blockchain.headers.subscribe", interactions[0].Method)
+	assert.False(t, interactions[0].Notification)
+	assert.Equal(t, "blockchain.headers.subscribe", interactions[1].Method)
+	assert.True(t, interactions[1].Notification)
+	assert.Equal(t, "blockchain.scripthash.get_balance", interactions[2].Method)
+	assert.JSONEq(t, `["8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"]`, string(interactions[2].Params))
+	assert.Equal(t, "server.banner", interactions[3].Method)
+
+	replayed := electrum.NewClient(ctx, electrum.NewReplayTransport(ctx, interactions), electrum.WithoutHandshake())
+	defer replayed.Shutdown()
+
+	exercise(ctx, t, replayed)
+}
+
+func TestReplayTransport_NotRecorded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := electrum.NewClient(ctx, electrum.NewReplayTransport(ctx, nil), electrum.WithoutHandshake())
+	defer client.Shutdown()
+
+	_, err := client.ServerBanner(ctx)
+	assert.ErrorIs(t, err, electrum.ErrNotRecorded)
+}
+
+func TestReplayTransport_NotRecordedKeepsClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interactions, err := electrum.ReadCassette(strings.NewReader(`{"method":"server.banner","result":"banner"}`))
+	require.NoError(t, err)
+
+	client := electrum.NewClient(ctx, electrum.NewReplayTransport(ctx, interactions), electrum.WithoutHandshake())
+	defer client.Shutdown()
+
+	_, err = client.ServerDonation(ctx)
+	require.ErrorIs(t, err, electrum.ErrNotRecorded)
+
+	var notRecorded *electrum.NotRecordedError
+	require.ErrorAs(t, err, &notRecorded)
+	assert.Equal(t, "server.donation_address", notRecorded.Method)
+
+	// it is not disguised as an error of the server
+	var apiErr *electrum.APIError
+	assert.False(t, errors.As(err, &apiErr))
+
+	// only the unrecorded request failed
+	assert.False(t, client.IsShutdown())
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "banner", banner)
+}
+
+func TestReplayTransport_Repeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interactions, err := electrum.ReadCassette(strings.NewReader(`
+{"method":"server.banner","result":"first"}
+{"method":"server.banner","result":"second"}
+{"method":"blockchain.estimatefee","params":[ 6 ],"result":0.0001}
+`))
+	require.NoError(t, err)
+
+	client := electrum.NewClient(ctx, electrum.NewReplayTransport(ctx, interactions), electrum.WithoutHandshake())
+	defer client.Shutdown()
+
+	for _, expected := range []string{"first", "second", "second"} {
+		banner, err := client.ServerBanner(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, banner)
+	}
+
+	fee, err := client.GetFee(ctx, 6)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0001, fee, 1e-9)
+}
+
+func TestReplayTransport_Error(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interactions, err := electrum.ReadCassette(strings.NewReader(`{"method":"server.banner","error":{"code":-32600,"message":"invalid request"}}`))
+	require.NoError(t, err)
+
+	client := electrum.NewClient(ctx, electrum.NewReplayTransport(ctx, interactions), electrum.WithoutHandshake())
+	defer client.Shutdown()
+
+	_, err = client.ServerBanner(ctx)
+
+	var apiErr *electrum.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, -32600, apiErr.Code)
+}
+
+func TestReadCassette_Invalid(t *testing.T) {
+	_, err := electrum.ReadCassette(strings.NewReader("{\"method\":\"server.ping\"}\nnot json\n"))
+	assert.ErrorContains(t, err, "cassette line 2")
+}
@@ -0,0 +1,307 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrInvalidDSProof throws an error if a double-spend proof can't be decoded.
+var ErrInvalidDSProof = errors.New("invalid double-spend proof")
+
+// DSProofResp represents the response to GetDSProof() and SubscribeDSProof().
+type DSProofResp struct {
+	Result *DSProofResult `json:"result"`
+}
+
+// DSProofResult represents a double-spend proof of a Bitcoin Cash server.
+type DSProofResult struct {
+	// DSPID is the hash of the proof.
+	DSPID string `json:"dspid"`
+	// TxID is the transaction the proof was created for.
+	TxID string `json:"txid"`
+	// Hex is the serialized proof.
+	Hex string `json:"hex"`
+	// Outpoint is the coin spent twice.
+	Outpoint DSProofOutpoint `json:"outpoint"`
+	// Descendants are TxID and the mempool transactions depending on it.
+	Descendants []string `json:"descendants"`
+}
+
+// DSProofOutpoint represents the coin spent by both transactions of a double-spend.
+type DSProofOutpoint struct {
+	TxID string `json:"txid"`
+	Vout uint32 `json:"vout"`
+}
+
+// DSProofNotif represent the notification to SubscribeDSProof().
+type DSProofNotif struct {
+	Params [2]json.RawMessage `json:"params"`
+}
+
+// DSProof is a decoded double-spend proof. It holds the signed parts of the two
+// conflicting spends of Outpoint.
+type DSProof struct {
+	Outpoint      DSProofOutpoint
+	FirstSpender  DSProofSpender
+	DoubleSpender DSProofSpender
+}
+
+// DSProofSpender represents one of the transactions spending the outpoint of a
+// double-spend proof.
+type DSProofSpender struct {
+	Version         uint32
+	Sequence        uint32
+	Locktime        uint32
+	HashPrevOutputs string
+	HashSequence    string
+	HashOutputs     string
+	// PushData are the pushes of the input script, usually the signature.
+	PushData [][]byte
+}
+
+// Decode decodes the serialized proof in Hex.
+func (r *DSProofResult) Decode() (*DSProof, error) {
+	return DecodeDSProof(r.Hex)
+}
+
+// DecodeDSProof decodes a double-spend proof serialized as hex.
+// https://documentation.cash/protocol/network/messages/dsproof-beta
+func DecodeDSProof(proof string) (*DSProof, error) {
+	data, err := hex.DecodeString(proof)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDSProof, err)
+	}
+
+	r := bytes.NewReader(data)
+
+	var result DSProof
+
+	var txid chainhash.Hash
+	if _, err := io.ReadFull(r, txid[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDSProof, err)
+	}
+
+	result.Outpoint.TxID = txid.String()
+
+	if err := binary.Read(r, binary.LittleEndian, &result.Outpoint.Vout); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDSProof, err)
+	}
+
+	for _, spender := range []*DSProofSpender{&result.FirstSpender, &result.DoubleSpender} {
+		if err := spender.decode(r); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDSProof, err)
+		}
+	}
+
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidDSProof, r.Len())
+	}
+
+	return &result, nil
+}
+
+func (s *DSProofSpender) decode(r *bytes.Reader) error {
+	for _, v := range []*uint32{&s.Version, &s.Sequence, &s.Locktime} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range []*string{&s.HashPrevOutputs, &s.HashSequence, &s.HashOutputs} {
+		var hash [chainhash.HashSize]byte
+		if _, err := io.ReadFull(r, hash[:]); err != nil {
+			return err
+		}
+
+		*v = hex.EncodeToString(hash[:])
+	}
+
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+
+	if count > uint64(r.Len()) {
+		return fmt.Errorf("push data count %d exceeds the proof", count)
+	}
+
+	s.PushData = make([][]byte, 0, count)
+	for range count {
+		data, err := wire.ReadVarBytes(r, 0, uint32(r.Len()), "push data")
+		if err != nil {
+			return err
+		}
+
+		s.PushData = append(s.PushData, data)
+	}
+
+	return nil
+}
+
+// GetDSProof returns the double-spend proof of a transaction or nil if there is none.
+// hash is a transaction id or the id of a proof.
+// https://electrum-cash-protocol.readthedocs.io/en/latest/protocol-methods.html#blockchain-transaction-dsproof-get
+func (s *Client) GetDSProof(ctx context.Context, hash string) (*DSProofResult, error) {
+	var resp DSProofResp
+
+	err := s.request(ctx, "blockchain.transaction.dsproof.get", []interface{}{hash}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+// ListDSProofsResp represents the response to ListDSProofs().
+type ListDSProofsResp struct {
+	Result []string `json:"result"`
+}
+
+// ListDSProofs returns the ids of all double-spend proofs known to the server.
+// https://electrum-cash-protocol.readthedocs.io/en/latest/protocol-methods.html#blockchain-transaction-dsproof-list
+func (s *Client) ListDSProofs(ctx context.Context) ([]string, error) {
+	var resp ListDSProofsResp
+
+	err := s.request(ctx, "blockchain.transaction.dsproof.list", []interface{}{}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+// SubscribeDSProof subscribes to receive the double-spend proofs of a transaction.
+// A proof already known to the server is delivered first. The subscription ends
+// and the channel is closed by UnsubscribeDSProof or when the client shuts down.
+// https://electrum-cash-protocol.readthedocs.io/en/latest/protocol-methods.html#blockchain-transaction-dsproof-subscribe
+func (s *Client) SubscribeDSProof(ctx context.Context, txHash string) (<-chan *DSProofResult, error) {
+	var resp DSProofResp
+
+	// Listen before subscribing, the first notification may directly follow the response.
+	ch := s.listenPush("blockchain.transaction.dsproof.subscribe")
+
+	err := s.request(ctx, "blockchain.transaction.dsproof.subscribe", []interface{}{txHash}, &resp)
+	if err != nil {
+		s.unlistenPush("blockchain.transaction.dsproof.subscribe", ch)
+		return nil, err
+	}
+
+	respChan := make(chan *DSProofResult, 10)
+	if resp.Result != nil {
+		respChan <- resp.Result
+	}
+
+	id := s.addSubscription(func(ctx context.Context) error {
+		var resp DSProofResp
+
+		err := s.request(ctx, "blockchain.transaction.dsproof.subscribe", []interface{}{txHash}, &resp)
+		if err != nil {
+			return err
+		}
+
+		if resp.Result != nil {
+			offer(respChan, resp.Result)
+		}
+
+		return nil
+	})
+
+	stop := make(chan struct{})
+	s.dsproofs.Change(func(val map[string][]chan struct{}) (map[string][]chan struct{}, error) {
+		val[txHash] = append(val[txHash], stop)
+		return val, nil
+	})
+
+	go func() {
+		defer close(respChan)
+
+		defer func() {
+			s.removeSubscription(id)
+			s.unlistenPush("blockchain.transaction.dsproof.subscribe", ch)
+			s.dsproofs.Change(func(val map[string][]chan struct{}) (map[string][]chan struct{}, error) {
+				val[txHash] = slices.DeleteFunc(val[txHash], func(c chan struct{}) bool {
+					return c == stop
+				})
+				if len(val[txHash]) == 0 {
+					delete(val, txHash)
+				}
+
+				return val, nil
+			})
+
+			// deliver may still hold the handler, make room for its last message
+			select {
+			case <-ch:
+			default:
+			}
+		}()
+
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-stop:
+				return
+			case msg, ok := <-ch:
+				if !ok || msg.err != nil {
+					return
+				}
+
+				var notif DSProofNotif
+
+				err := json.Unmarshal(msg.content, &notif)
+				if err != nil {
+					s.log.Warnf("Unmarshaling of SubscribeDSProof failed: %v", err)
+					continue
+				}
+
+				var hash string
+				var proof *DSProofResult
+
+				if json.Unmarshal(notif.Params[0], &hash) != nil || hash != txHash {
+					continue
+				}
+
+				if err := json.Unmarshal(notif.Params[1], &proof); err != nil || proof == nil {
+					continue
+				}
+
+				select {
+				case respChan <- proof:
+				case <-s.quit:
+					return
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+
+	return respChan, nil
+}
+
+// UnsubscribeDSProof cancels the double-spend proof subscriptions of a transaction
+// on the server and closes their channels.
+// https://electrum-cash-protocol.readthedocs.io/en/latest/protocol-methods.html#blockchain-transaction-dsproof-unsubscribe
+func (s *Client) UnsubscribeDSProof(ctx context.Context, txHash string) error {
+	s.dsproofs.Change(func(val map[string][]chan struct{}) (map[string][]chan struct{}, error) {
+		for _, stop := range val[txHash] {
+			close(stop)
+		}
+
+		delete(val, txHash)
+		return val, nil
+	})
+
+	return s.request(ctx, "blockchain.transaction.dsproof.unsubscribe", []interface{}{txHash}, nil)
+}
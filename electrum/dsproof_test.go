@@ -0,0 +1,186 @@
+package electrum_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// encodeDSProofSpender serializes a spender of a double-spend proof.
+func encodeDSProofSpender(buf *bytes.Buffer, version, sequence, locktime uint32, fill byte, sig []byte) {
+	binary.Write(buf, binary.LittleEndian, version)
+	binary.Write(buf, binary.LittleEndian, sequence)
+	binary.Write(buf, binary.LittleEndian, locktime)
+
+	for i := range 3 {
+		buf.Write(bytes.Repeat([]byte{fill + byte(i)}, 32))
+	}
+
+	buf.WriteByte(1)
+	buf.WriteByte(byte(len(sig)))
+	buf.Write(sig)
+}
+
+func testDSProof() string {
+	var buf bytes.Buffer
+
+	txid := make([]byte, 32)
+	txid[0] = 0xab
+	buf.Write(txid)
+	binary.Write(&buf, binary.LittleEndian, uint32(3))
+
+	encodeDSProofSpender(&buf, 2, 0xffffffff, 0, 0x10, []byte{0x30, 0x01})
+	encodeDSProofSpender(&buf, 1, 0xfffffffe, 100, 0x20, []byte{0x30, 0x02, 0x41})
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestDecodeDSProof(t *testing.T) {
+	proof, err := electrum.DecodeDSProof(testDSProof())
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.Repeat("00", 31)+"ab", proof.Outpoint.TxID)
+	assert.Equal(t, uint32(3), proof.Outpoint.Vout)
+
+	assert.Equal(t, electrum.DSProofSpender{
+		Version:         2,
+		Sequence:        0xffffffff,
+		Locktime:        0,
+		HashPrevOutputs: strings.Repeat("10", 32),
+		HashSequence:    strings.Repeat("11", 32),
+		HashOutputs:     strings.Repeat("12", 32),
+		PushData:        [][]byte{{0x30, 0x01}},
+	}, proof.FirstSpender)
+
+	assert.Equal(t, electrum.DSProofSpender{
+		Version:         1,
+		Sequence:        0xfffffffe,
+		Locktime:        100,
+		HashPrevOutputs: strings.Repeat("20", 32),
+		HashSequence:    strings.Repeat("21", 32),
+		HashOutputs:     strings.Repeat("22", 32),
+		PushData:        [][]byte{{0x30, 0x02, 0x41}},
+	}, proof.DoubleSpender)
+
+	result := electrum.DSProofResult{Hex: testDSProof()}
+	decoded, err := result.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, proof, decoded)
+}
+
+func TestDecodeDSProof_Invalid(t *testing.T) {
+	valid := testDSProof()
+
+	tests := []struct {
+		name  string
+		proof string
+	}{
+		{"hex", "xyz"},
+		{"empty", ""},
+		{"truncated", valid[:len(valid)-2]},
+		{"trailing", valid + "00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := electrum.DecodeDSProof(tt.proof)
+			assert.ErrorIs(t, err, electrum.ErrInvalidDSProof)
+		})
+	}
+}
+
+func TestGetDSProof(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport)
+	defer client.Shutdown()
+
+	expected := &electrum.DSProofResult{
+		DSPID:       "dspid",
+		TxID:        "tx",
+		Hex:         testDSProof(),
+		Outpoint:    electrum.DSProofOutpoint{TxID: "prev", Vout: 3},
+		Descendants: []string{"tx", "child"},
+	}
+
+	stop := startAutoResponder(transport, expected)
+	proof, err := client.GetDSProof(ctx, "tx")
+	stop()
+
+	require.NoError(t, err)
+	assert.Equal(t, expected, proof)
+
+	stop = startAutoResponder(transport, nil)
+	proof, err = client.GetDSProof(ctx, "other")
+	stop()
+
+	require.NoError(t, err)
+	assert.Nil(t, proof)
+
+	stop = startAutoResponder(transport, []string{"a", "b"})
+	defer stop()
+
+	ids, err := client.ListDSProofs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids)
+}
+
+func TestSubscribeDSProof(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport)
+	defer client.Shutdown()
+
+	stop := startAutoResponder(transport, nil)
+
+	// the subscription outlives the context of the request
+	sub, cancel := context.WithCancel(ctx)
+	proofs, err := client.SubscribeDSProof(sub, "tx")
+	require.NoError(t, err)
+	cancel()
+
+	stop()
+
+	proof := &electrum.DSProofResult{DSPID: "dspid", TxID: "tx", Hex: testDSProof()}
+
+	require.NoError(t, transport.notify("blockchain.transaction.dsproof.subscribe", []any{"other", proof}))
+	require.NoError(t, transport.notify("blockchain.transaction.dsproof.subscribe", []any{"tx", nil}))
+	require.NoError(t, transport.notify("blockchain.transaction.dsproof.subscribe", []any{"tx", proof}))
+
+	select {
+	case received := <-proofs:
+		assert.Equal(t, proof, received)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no proof received")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.UnsubscribeDSProof(ctx, "tx")
+	}()
+
+	method := respond(t, transport, func(string) any { return true })
+	assert.Equal(t, "blockchain.transaction.dsproof.unsubscribe", method)
+	require.NoError(t, <-done)
+
+	select {
+	case _, ok := <-proofs:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "channel not closed")
+	}
+
+	require.Eventually(t, func() bool {
+		return client.Stats().Subscriptions == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,441 @@
+package electrumtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// blockInterval is the time between the timestamps of two mined blocks.
+const blockInterval = 10 * time.Minute
+
+var (
+	// ErrKnownTransaction throws an error if a transaction is already in the mempool
+	// or the chain.
+	ErrKnownTransaction = errors.New("transaction already known")
+
+	// ErrReorgDepth throws an error if a reorg would disconnect the genesis block.
+	ErrReorgDepth = errors.New("reorg depth exceeds the chain")
+)
+
+// anyoneCanSpend is the output script of the mined coinbase transactions.
+var anyoneCanSpend = []byte{txscript.OP_TRUE}
+
+// Height returns the height of the chain tip.
+func (s *Server) Height() int32 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.height()
+}
+
+// Block returns the block at height or nil.
+func (s *Server) Block(height int32) *wire.MsgBlock {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if height < 0 || height > s.height() {
+		return nil
+	}
+
+	return s.blocks[height]
+}
+
+// Mempool returns the unconfirmed transactions in the order they have been added.
+func (s *Server) Mempool() []*wire.MsgTx {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return slices.Clone(s.mempool)
+}
+
+// AddTransaction adds tx to the mempool and notifies the subscribers.
+func (s *Server) AddTransaction(tx *wire.MsgTx) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.addTransaction(tx); err != nil {
+		return err
+	}
+
+	s.notify()
+
+	return nil
+}
+
+// Pay adds a transaction paying value to pkScript to the mempool. The input of the
+// transaction is made up, so its fee is unknown.
+func (s *Server) Pay(pkScript []byte, value int64) *wire.MsgTx {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.nonce++
+
+	var prev chainhash.Hash
+	binary.LittleEndian.PutUint64(prev[:], s.nonce)
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prev, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(value, pkScript))
+
+	s.mempool = append(s.mempool, tx)
+	s.notify()
+
+	return tx
+}
+
+// RemoveTransaction evicts a transaction from the mempool and reports whether it
+// has been there.
+func (s *Server) RemoveTransaction(txid chainhash.Hash) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := len(s.mempool)
+	s.mempool = slices.DeleteFunc(s.mempool, func(tx *wire.MsgTx) bool {
+		return tx.TxHash() == txid
+	})
+
+	if len(s.mempool) == n {
+		return false
+	}
+
+	s.notify()
+
+	return true
+}
+
+// Mine mines n blocks. The first block confirms the whole mempool.
+func (s *Server) Mine(n int) []*wire.MsgBlock {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blocks := make([]*wire.MsgBlock, 0, n)
+	for i := 0; i < n; i++ {
+		blocks = append(blocks, s.mine(s.mempool))
+		s.mempool = nil
+	}
+
+	s.notify()
+
+	return blocks
+}
+
+// Confirm mines a block with txs. Transactions not yet known are confirmed
+// without passing the mempool.
+func (s *Server) Confirm(txs ...*wire.MsgTx) *wire.MsgBlock {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.mempool = slices.DeleteFunc(s.mempool, func(tx *wire.MsgTx) bool {
+		return slices.Contains(txs, tx)
+	})
+
+	block := s.mine(txs)
+	s.notify()
+
+	return block
+}
+
+// Reorg disconnects the last depth blocks and mines length empty blocks instead.
+// The transactions of the disconnected blocks go back to the mempool.
+func (s *Server) Reorg(depth, length int) ([]*wire.MsgBlock, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if depth < 0 || depth > int(s.height()) {
+		return nil, fmt.Errorf("%w: %d > %d", ErrReorgDepth, depth, s.height())
+	}
+
+	fork := len(s.blocks) - depth
+
+	var disconnected []*wire.MsgTx
+	for _, block := range s.blocks[fork:] {
+		disconnected = append(disconnected, block.Transactions[1:]...)
+	}
+
+	s.blocks = s.blocks[:fork]
+	s.mempool = append(disconnected, s.mempool...)
+
+	blocks := make([]*wire.MsgBlock, 0, length)
+	for i := 0; i < length; i++ {
+		blocks = append(blocks, s.mine(nil))
+	}
+
+	s.notify()
+
+	return blocks, nil
+}
+
+func (s *Server) height() int32 {
+	return int32(len(s.blocks) - 1)
+}
+
+func (s *Server) addTransaction(tx *wire.MsgTx) error {
+	txid := tx.TxHash()
+	if _, ok := s.index().txs[txid]; ok {
+		return fmt.Errorf("%w: %s", ErrKnownTransaction, txid)
+	}
+
+	s.mempool = append(s.mempool, tx)
+
+	return nil
+}
+
+// mine appends a block with a coinbase and txs to the chain. The header carries a
+// valid proof of work for the regtest difficulty.
+func (s *Server) mine(txs []*wire.MsgTx) *wire.MsgBlock {
+	height := s.height() + 1
+	prev := s.blocks[height-1].Header
+
+	s.nonce++
+
+	// BIP34 height and an extra nonce, blocks mined at the same height after a
+	// reorg must differ.
+	sigScript, _ := txscript.NewScriptBuilder().AddInt64(int64(height)).AddInt64(int64(s.nonce)).Script()
+
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), sigScript, nil))
+	coinbase.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(height, s.params), anyoneCanSpend))
+
+	block := &wire.MsgBlock{
+		Transactions: append([]*wire.MsgTx{coinbase}, txs...),
+	}
+
+	hashes := make([]chainhash.Hash, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		hashes[i] = tx.TxHash()
+	}
+	_, root := merkleBranch(hashes, 0)
+
+	block.Header = wire.BlockHeader{
+		Version:    0x20000000,
+		PrevBlock:  prev.BlockHash(),
+		MerkleRoot: root,
+		Timestamp:  prev.Timestamp.Add(blockInterval),
+		Bits:       s.params.PowLimitBits,
+	}
+
+	target := blockchain.CompactToBig(block.Header.Bits)
+	for {
+		hash := block.Header.BlockHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			break
+		}
+
+		block.Header.Nonce++
+	}
+
+	s.blocks = append(s.blocks, block)
+
+	return block
+}
+
+// txRef locates a transaction in the chain or the mempool.
+type txRef struct {
+	tx       *wire.MsgTx
+	height   int32
+	position int
+	mempool  bool
+}
+
+// output is a transaction output with the scripthash of its script.
+type output struct {
+	scripthash string
+	value      int64
+	ref        txRef
+}
+
+// chainIndex maps transactions and outputs of the chain and the mempool.
+type chainIndex struct {
+	// txs in chain order followed by the mempool
+	order  []chainhash.Hash
+	txs    map[chainhash.Hash]txRef
+	funded map[wire.OutPoint]output
+	spent  map[wire.OutPoint]txRef
+}
+
+// index builds the index of the current chain and mempool.
+func (s *Server) index() *chainIndex {
+	idx := &chainIndex{
+		txs:    make(map[chainhash.Hash]txRef),
+		funded: make(map[wire.OutPoint]output),
+		spent:  make(map[wire.OutPoint]txRef),
+	}
+
+	add := func(ref txRef) {
+		txid := ref.tx.TxHash()
+
+		idx.order = append(idx.order, txid)
+		idx.txs[txid] = ref
+
+		for i, out := range ref.tx.TxOut {
+			idx.funded[wire.OutPoint{Hash: txid, Index: uint32(i)}] = output{
+				scripthash: ScriptHash(out.PkScript),
+				value:      out.Value,
+				ref:        ref,
+			}
+		}
+
+		if !blockchain.IsCoinBaseTx(ref.tx) {
+			for _, in := range ref.tx.TxIn {
+				idx.spent[in.PreviousOutPoint] = ref
+			}
+		}
+	}
+
+	for height, block := range s.blocks {
+		for position, tx := range block.Transactions {
+			add(txRef{tx: tx, height: int32(height), position: position})
+		}
+	}
+
+	for _, tx := range s.mempool {
+		add(txRef{tx: tx, mempool: true})
+	}
+
+	return idx
+}
+
+// touches reports whether tx pays to or spends from scripthash.
+func (idx *chainIndex) touches(tx *wire.MsgTx, scripthash string) bool {
+	for _, out := range tx.TxOut {
+		if ScriptHash(out.PkScript) == scripthash {
+			return true
+		}
+	}
+
+	if blockchain.IsCoinBaseTx(tx) {
+		return false
+	}
+
+	for _, in := range tx.TxIn {
+		if funded, ok := idx.funded[in.PreviousOutPoint]; ok && funded.scripthash == scripthash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// historyEntry is an entry of the history of a scripthash.
+type historyEntry struct {
+	txid    chainhash.Hash
+	height  int32
+	fee     int64
+	mempool bool
+}
+
+// history returns the confirmed transactions of scripthash in chain order followed
+// by the mempool transactions. Mempool transactions with unconfirmed inputs get
+// height -1.
+func (idx *chainIndex) history(scripthash string) []historyEntry {
+	var entries []historyEntry
+
+	for _, txid := range idx.order {
+		ref := idx.txs[txid]
+		if !idx.touches(ref.tx, scripthash) {
+			continue
+		}
+
+		entry := historyEntry{txid: txid, height: ref.height, mempool: ref.mempool}
+		if ref.mempool {
+			entry.fee = idx.fee(ref.tx)
+
+			for _, in := range ref.tx.TxIn {
+				if parent, ok := idx.txs[in.PreviousOutPoint.Hash]; ok && parent.mempool {
+					entry.height = -1
+				}
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// fee returns the fee of tx or 0 if an input is unknown.
+func (idx *chainIndex) fee(tx *wire.MsgTx) int64 {
+	var fee int64
+
+	for _, in := range tx.TxIn {
+		funded, ok := idx.funded[in.PreviousOutPoint]
+		if !ok {
+			return 0
+		}
+
+		fee += funded.value
+	}
+
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+
+	return fee
+}
+
+// status returns the status hash of scripthash or nil if it has no history.
+// https://electrumx.readthedocs.io/en/latest/protocol-basics.html#status
+func (idx *chainIndex) status(scripthash string) *string {
+	entries := idx.history(scripthash)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		fmt.Fprintf(&buf, "%s:%d:", entry.txid, entry.height)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	status := hex.EncodeToString(sum[:])
+
+	return &status
+}
+
+// ScriptHash returns the Electrum scripthash of pkScript.
+// https://electrumx.readthedocs.io/en/latest/protocol-basics.html#script-hashes
+func ScriptHash(pkScript []byte) string {
+	sum := sha256.Sum256(pkScript)
+	slices.Reverse(sum[:])
+
+	return hex.EncodeToString(sum[:])
+}
+
+// merkleBranch returns the merkle branch of the leaf at index and the merkle root.
+func merkleBranch(leaves []chainhash.Hash, index int) ([]chainhash.Hash, chainhash.Hash) {
+	var branch []chainhash.Hash
+
+	level := slices.Clone(leaves)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		branch = append(branch, level[index^1])
+
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			var pair [chainhash.HashSize * 2]byte
+			copy(pair[:], level[2*i][:])
+			copy(pair[chainhash.HashSize:], level[2*i+1][:])
+
+			next[i] = chainhash.DoubleHashH(pair[:])
+		}
+
+		level = next
+		index /= 2
+	}
+
+	return branch, level[0]
+}
@@ -0,0 +1,89 @@
+package electrumtest_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum/electrumtest"
+)
+
+func txs(block *wire.MsgBlock) []*btcutil.Tx {
+	result := make([]*btcutil.Tx, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		result[i] = btcutil.NewTx(tx)
+	}
+
+	return result
+}
+
+func TestServer_Mine(t *testing.T) {
+	server := electrumtest.NewServer()
+
+	blocks := server.Mine(3)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, int32(3), server.Height())
+
+	prev := server.Block(0).Header.BlockHash()
+	for _, block := range blocks {
+		assert.Equal(t, prev, block.Header.PrevBlock)
+
+		// valid proof of work and merkle root
+		hash := block.Header.BlockHash()
+		assert.LessOrEqual(t, blockchain.HashToBig(&hash).Cmp(blockchain.CompactToBig(chaincfg.RegressionNetParams.PowLimitBits)), 0)
+
+		merkles := blockchain.BuildMerkleTreeStore(txs(block), false)
+		assert.Equal(t, *merkles[len(merkles)-1], block.Header.MerkleRoot)
+
+		prev = hash
+	}
+
+	assert.Nil(t, server.Block(4))
+}
+
+func TestServer_Mempool(t *testing.T) {
+	server := electrumtest.NewServer()
+
+	first := server.Pay([]byte{0x51}, 1000)
+	second := server.Pay([]byte{0x51}, 2000)
+	assert.Equal(t, []*wire.MsgTx{first, second}, server.Mempool())
+
+	assert.ErrorIs(t, server.AddTransaction(first), electrumtest.ErrKnownTransaction)
+
+	assert.True(t, server.RemoveTransaction(first.TxHash()))
+	assert.False(t, server.RemoveTransaction(first.TxHash()))
+
+	block := server.Confirm(first)
+	assert.Equal(t, []*wire.MsgTx{first}, block.Transactions[1:])
+	assert.Equal(t, []*wire.MsgTx{second}, server.Mempool())
+
+	block = server.Mine(1)[0]
+	assert.Equal(t, []*wire.MsgTx{second}, block.Transactions[1:])
+	assert.Empty(t, server.Mempool())
+}
+
+func TestServer_ReorgDepth(t *testing.T) {
+	server := electrumtest.NewServer()
+	server.Mine(2)
+
+	tx := server.Pay([]byte{0x51}, 1000)
+	old := server.Mine(1)[0]
+
+	blocks, err := server.Reorg(1, 0)
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+	assert.Equal(t, int32(2), server.Height())
+	assert.Equal(t, []*wire.MsgTx{tx}, server.Mempool())
+
+	blocks, err = server.Reorg(0, 1)
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.NotEqual(t, old.Header.BlockHash(), blocks[0].Header.BlockHash())
+
+	_, err = server.Reorg(4, 1)
+	assert.ErrorIs(t, err, electrumtest.ErrReorgDepth)
+}
@@ -0,0 +1,762 @@
+package electrumtest
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// JSON-RPC error codes returned by the server.
+const (
+	codeBadRequest     = 1
+	codeInvalidRequest = -32600
+	codeUnknownMethod  = -32601
+	codeInvalidParams  = -32602
+)
+
+// request is a JSON-RPC request sent by a client.
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// handle answers a single request or a batch. It returns nil if nothing has to
+// be sent back.
+func (s *Server) handle(sess *session, line []byte) []byte {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	if trimmed[0] != '[' {
+		var req request
+		if err := json.Unmarshal(trimmed, &req); err != nil {
+			return reply(nil, nil, &electrum.APIError{Code: codeInvalidRequest, Message: err.Error()})
+		}
+
+		return s.call(sess, req)
+	}
+
+	var batch []request
+	if err := json.Unmarshal(trimmed, &batch); err != nil {
+		return reply(nil, nil, &electrum.APIError{Code: codeInvalidRequest, Message: err.Error()})
+	}
+
+	replies := make([]json.RawMessage, 0, len(batch))
+	for _, req := range batch {
+		if msg := s.call(sess, req); msg != nil {
+			replies = append(replies, msg)
+		}
+	}
+
+	msg, _ := json.Marshal(replies)
+
+	return msg
+}
+
+// call runs the method of req. Requests without id are notifications and get no reply.
+func (s *Server) call(sess *session, req request) []byte {
+	result, err := s.dispatch(sess, req.Method, parseParams(req.Params))
+	if req.ID == nil {
+		return nil
+	}
+
+	return reply(req.ID, result, err)
+}
+
+func reply(id json.RawMessage, result any, err error) []byte {
+	msg := map[string]any{"jsonrpc": "2.0", "id": id}
+
+	if err != nil {
+		var apiErr *electrum.APIError
+		if !errors.As(err, &apiErr) {
+			apiErr = &electrum.APIError{Code: codeBadRequest, Message: err.Error()}
+		}
+
+		msg["error"] = apiErr
+	} else {
+		msg["result"] = result
+	}
+
+	data, _ := json.Marshal(msg)
+
+	return data
+}
+
+func (s *Server) dispatch(sess *session, method string, p params) (any, error) {
+	switch method {
+	case "server.version":
+		return s.version(p)
+	case "server.ping":
+		return nil, nil
+	case "server.banner":
+		return s.banner, nil
+	case "server.donation_address":
+		return "", nil
+	case "server.features":
+		return s.features(), nil
+	case "server.peers.subscribe":
+		return []any{}, nil
+	case "server.add_peer":
+		return false, nil
+	case "blockchain.estimatefee":
+		return s.fee, nil
+	case "blockchain.relayfee":
+		return s.relayFee, nil
+	case "mempool.get_fee_histogram":
+		return s.feeHistogram(), nil
+	case "blockchain.headers.subscribe":
+		sess.headers = true
+		sess.tip = s.blocks[s.height()].Header.BlockHash()
+		return s.headerResult(s.height()), nil
+	case "blockchain.block.header":
+		return s.blockHeader(p)
+	case "blockchain.block.headers":
+		return s.blockHeaders(p)
+	case "blockchain.scripthash.subscribe":
+		return s.subscribeScripthash(sess, p)
+	case "blockchain.scripthash.unsubscribe":
+		return s.unsubscribeScripthash(sess, p)
+	case "blockchain.scripthash.get_balance":
+		return s.balance(p)
+	case "blockchain.scripthash.get_history":
+		return s.scripthashHistory(p, false)
+	case "blockchain.scripthash.get_mempool":
+		return s.scripthashHistory(p, true)
+	case "blockchain.scripthash.listunspent":
+		return s.listUnspent(p)
+	case "blockchain.transaction.broadcast":
+		return s.broadcast(p)
+	case "blockchain.transaction.get":
+		return s.transaction(p)
+	case "blockchain.transaction.get_merkle":
+		return s.transactionMerkle(p)
+	case "blockchain.transaction.id_from_pos":
+		return s.idFromPos(p)
+	}
+
+	return nil, &electrum.APIError{Code: codeUnknownMethod, Message: "unknown method " + method}
+}
+
+func (s *Server) version(p params) (any, error) {
+	// the protocol version is a single version or a [min, max] range
+	clientMin, clientMax := ProtocolMin, ProtocolMin
+	if raw := p.raw(1); raw != nil {
+		var single string
+		var bounds [2]string
+
+		if err := json.Unmarshal(raw, &single); err == nil {
+			clientMin, clientMax = single, single
+		} else if err := json.Unmarshal(raw, &bounds); err == nil {
+			clientMin, clientMax = bounds[0], bounds[1]
+		} else {
+			return nil, invalidParams("protocol version")
+		}
+	}
+
+	negotiated := ProtocolMax
+	if electrum.CompareVersions(clientMax, negotiated) < 0 {
+		negotiated = clientMax
+	}
+
+	if electrum.CompareVersions(negotiated, ProtocolMin) < 0 || electrum.CompareVersions(negotiated, clientMin) < 0 {
+		return nil, &electrum.APIError{Code: codeBadRequest, Message: "unsupported protocol version: " + clientMax}
+	}
+
+	return []string{ServerVersion, negotiated}, nil
+}
+
+func (s *Server) features() *electrum.ServerFeaturesResult {
+	return &electrum.ServerFeaturesResult{
+		GenesisHash:   s.params.GenesisHash.String(),
+		Hosts:         map[string]electrum.Host{},
+		ProtocolMax:   ProtocolMax,
+		ProtocolMin:   ProtocolMin,
+		ServerVersion: ServerVersion,
+		HashFunction:  "sha256",
+	}
+}
+
+// feeHistogram groups the mempool by fee rate in satoshis per virtual byte.
+// Transactions with unknown fee are left out.
+func (s *Server) feeHistogram() [][2]uint64 {
+	idx := s.index()
+	sizes := make(map[uint64]uint64)
+
+	for _, tx := range s.mempool {
+		fee := idx.fee(tx)
+		if fee <= 0 {
+			continue
+		}
+
+		vsize := uint64((blockchain.GetTransactionWeight(btcutil.NewTx(tx)) + 3) / 4)
+		sizes[uint64(fee)/vsize] += vsize
+	}
+
+	histogram := make([][2]uint64, 0, len(sizes))
+	for rate, vsize := range sizes {
+		histogram = append(histogram, [2]uint64{rate, vsize})
+	}
+
+	slices.SortFunc(histogram, func(a, b [2]uint64) int {
+		return cmp.Compare(b[0], a[0])
+	})
+
+	return histogram
+}
+
+func (s *Server) headerResult(height int32) *electrum.SubscribeHeadersResult {
+	return &electrum.SubscribeHeadersResult{
+		Height: height,
+		Hex:    s.headerHex(height),
+	}
+}
+
+func (s *Server) headerHex(height int32) string {
+	var buf bytes.Buffer
+	s.blocks[height].Header.Serialize(&buf)
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// headerBranch returns the merkle branch and root of the header at height in the
+// tree of all headers up to cp.
+func (s *Server) headerBranch(height, cp int32) ([]string, string) {
+	hashes := make([]chainhash.Hash, cp+1)
+	for i := range hashes {
+		hashes[i] = s.blocks[i].Header.BlockHash()
+	}
+
+	branch, root := merkleBranch(hashes, int(height))
+
+	return hashStrings(branch), root.String()
+}
+
+// checkpoint validates the optional checkpoint height of a header request.
+func (s *Server) checkpoint(p params, index int, last int32) (int32, error) {
+	cp, err := p.optionalUint(index)
+	if err != nil {
+		return 0, err
+	}
+
+	if cp != 0 && (int32(cp) < last || int32(cp) > s.height()) {
+		return 0, invalidParams(fmt.Sprintf("invalid checkpoint height %d", cp))
+	}
+
+	return int32(cp), nil
+}
+
+func (s *Server) blockHeader(p params) (any, error) {
+	height, err := p.uint(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if int32(height) > s.height() {
+		return nil, invalidParams(fmt.Sprintf("height %d out of range", height))
+	}
+
+	cp, err := s.checkpoint(p, 1, int32(height))
+	if err != nil {
+		return nil, err
+	}
+
+	if cp == 0 {
+		return s.headerHex(int32(height)), nil
+	}
+
+	branch, root := s.headerBranch(int32(height), cp)
+
+	return &electrum.GetBlockHeaderResult{
+		Branch: branch,
+		Header: s.headerHex(int32(height)),
+		Root:   root,
+	}, nil
+}
+
+func (s *Server) blockHeaders(p params) (any, error) {
+	start, err := p.uint(0)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := p.uint(1)
+	if err != nil {
+		return nil, err
+	}
+
+	count = min(count, MaxHeaders, uint32(max(s.height()+1-int32(start), 0)))
+
+	cp, err := s.checkpoint(p, 2, int32(start+count)-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers strings.Builder
+	for height := int32(start); height < int32(start+count); height++ {
+		headers.WriteString(s.headerHex(height))
+	}
+
+	result := &electrum.GetBlockHeadersResult{
+		Count:   count,
+		Headers: headers.String(),
+		Max:     MaxHeaders,
+	}
+
+	if cp != 0 && count > 0 {
+		result.Branch, result.Root = s.headerBranch(int32(start+count)-1, cp)
+	}
+
+	return result, nil
+}
+
+func (s *Server) subscribeScripthash(sess *session, p params) (any, error) {
+	scripthash, err := p.scripthash(0)
+	if err != nil {
+		return nil, err
+	}
+
+	status := s.index().status(scripthash)
+	sess.scripthashes[scripthash] = status
+
+	return status, nil
+}
+
+func (s *Server) unsubscribeScripthash(sess *session, p params) (any, error) {
+	scripthash, err := p.scripthash(0)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := sess.scripthashes[scripthash]
+	delete(sess.scripthashes, scripthash)
+
+	return ok, nil
+}
+
+func (s *Server) balance(p params) (any, error) {
+	scripthash, err := p.scripthash(0)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := s.index()
+
+	// outputs spent by the mempool still count as confirmed, the unconfirmed
+	// balance is the change by the mempool
+	var balance electrum.GetBalanceResult
+	for outpoint, funded := range idx.funded {
+		if funded.scripthash != scripthash {
+			continue
+		}
+
+		spender, spent := idx.spent[outpoint]
+		if spent && !spender.mempool {
+			continue
+		}
+
+		if funded.ref.mempool {
+			balance.Unconfirmed += float64(funded.value)
+		} else {
+			balance.Confirmed += float64(funded.value)
+		}
+
+		if spent {
+			balance.Unconfirmed -= float64(funded.value)
+		}
+	}
+
+	return balance, nil
+}
+
+func (s *Server) scripthashHistory(p params, mempool bool) (any, error) {
+	scripthash, err := p.scripthash(0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*electrum.GetMempoolResult{}
+	for _, entry := range s.index().history(scripthash) {
+		if mempool && !entry.mempool {
+			continue
+		}
+
+		item := &electrum.GetMempoolResult{
+			Hash:   entry.txid.String(),
+			Height: entry.height,
+		}
+
+		if entry.mempool {
+			item.Fee = uint32(entry.fee)
+		}
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+func (s *Server) listUnspent(p params) (any, error) {
+	scripthash, err := p.scripthash(0)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := s.index()
+
+	result := []*electrum.ListUnspentResult{}
+	for outpoint, funded := range idx.funded {
+		if funded.scripthash != scripthash {
+			continue
+		}
+
+		if _, ok := idx.spent[outpoint]; ok {
+			continue
+		}
+
+		item := &electrum.ListUnspentResult{
+			Position: outpoint.Index,
+			Hash:     outpoint.Hash.String(),
+			Value:    uint64(funded.value),
+		}
+
+		if !funded.ref.mempool {
+			item.Height = uint32(funded.ref.height)
+		}
+
+		result = append(result, item)
+	}
+
+	// confirmed first, in chain order
+	slices.SortFunc(result, func(a, b *electrum.ListUnspentResult) int {
+		if a.Height != b.Height {
+			if a.Height == 0 {
+				return 1
+			} else if b.Height == 0 {
+				return -1
+			}
+
+			return int(a.Height) - int(b.Height)
+		}
+
+		if c := strings.Compare(a.Hash, b.Hash); c != 0 {
+			return c
+		}
+
+		return int(a.Position) - int(b.Position)
+	})
+
+	return result, nil
+}
+
+func (s *Server) broadcast(p params) (any, error) {
+	raw, err := p.string(0)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, invalidParams(err.Error())
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, &electrum.APIError{Code: codeBadRequest, Message: "TX decode failed: " + err.Error()}
+	}
+
+	if err := s.addTransaction(tx); err != nil {
+		return nil, err
+	}
+
+	s.notify()
+
+	return tx.TxHash().String(), nil
+}
+
+// lookup finds the transaction with the hash in parameter index.
+func (s *Server) lookup(p params, index int) (txRef, *chainIndex, error) {
+	txid, err := p.hash(index)
+	if err != nil {
+		return txRef{}, nil, err
+	}
+
+	idx := s.index()
+
+	ref, ok := idx.txs[txid]
+	if !ok {
+		return txRef{}, nil, fmt.Errorf("no such mempool or blockchain transaction: %s", txid)
+	}
+
+	return ref, idx, nil
+}
+
+func (s *Server) transaction(p params) (any, error) {
+	ref, _, err := s.lookup(p, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := txHex(ref.tx)
+
+	verbose, err := p.optionalBool(1)
+	if err != nil || !verbose {
+		return raw, err
+	}
+
+	result := &electrum.GetTransactionResult{
+		Hash:     ref.tx.TxHash().String(),
+		Hex:      raw,
+		Locktime: ref.tx.LockTime,
+		Size:     uint32(ref.tx.SerializeSize()),
+		Version:  uint32(ref.tx.Version),
+		Vin:      make([]electrum.Vin, 0, len(ref.tx.TxIn)),
+		Vout:     make([]electrum.Vout, 0, len(ref.tx.TxOut)),
+	}
+
+	if !ref.mempool {
+		header := s.blocks[ref.height].Header
+
+		result.Blockhash = header.BlockHash().String()
+		result.Blocktime = uint64(header.Timestamp.Unix())
+		result.Time = result.Blocktime
+		result.Confirmations = s.height() - ref.height + 1
+	}
+
+	for _, in := range ref.tx.TxIn {
+		vin := electrum.Vin{Sequence: in.Sequence}
+
+		if blockchain.IsCoinBaseTx(ref.tx) {
+			vin.Coinbase = hex.EncodeToString(in.SignatureScript)
+		} else {
+			vin.TxID = in.PreviousOutPoint.Hash.String()
+			vin.Vout = in.PreviousOutPoint.Index
+			vin.ScriptSig = &electrum.ScriptSig{Hex: hex.EncodeToString(in.SignatureScript)}
+			vin.ScriptSig.Asm, _ = txscript.DisasmString(in.SignatureScript)
+		}
+
+		result.Vin = append(result.Vin, vin)
+	}
+
+	for n, out := range ref.tx.TxOut {
+		class, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(out.PkScript, s.params)
+		asm, _ := txscript.DisasmString(out.PkScript)
+
+		vout := electrum.Vout{
+			N:     uint32(n),
+			Value: btcutil.Amount(out.Value).ToBTC(),
+			ScriptPubkey: electrum.ScriptPubkey{
+				Asm:     asm,
+				Hex:     hex.EncodeToString(out.PkScript),
+				ReqSigs: uint32(reqSigs),
+				Type:    class.String(),
+			},
+		}
+
+		for _, addr := range addrs {
+			vout.ScriptPubkey.Addresses = append(vout.ScriptPubkey.Addresses, addr.EncodeAddress())
+		}
+
+		result.Vout = append(result.Vout, vout)
+	}
+
+	return result, nil
+}
+
+func (s *Server) transactionMerkle(p params) (any, error) {
+	ref, _, err := s.lookup(p, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := p.uint(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.mempool || ref.height != int32(height) {
+		return nil, fmt.Errorf("tx %s not in block at height %d", ref.tx.TxHash(), height)
+	}
+
+	branch, _ := s.txBranch(ref.height, ref.position)
+
+	return &electrum.GetMerkleProofResult{
+		Merkle:   branch,
+		Height:   height,
+		Position: uint32(ref.position),
+	}, nil
+}
+
+func (s *Server) idFromPos(p params) (any, error) {
+	height, err := p.uint(0)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := p.uint(1)
+	if err != nil {
+		return nil, err
+	}
+
+	merkle, err := p.optionalBool(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if int32(height) > s.height() {
+		return nil, fmt.Errorf("block at height %d not found", height)
+	}
+
+	txs := s.blocks[height].Transactions
+	if int(position) >= len(txs) {
+		return nil, fmt.Errorf("no tx at position %d in block at height %d", position, height)
+	}
+
+	txid := txs[position].TxHash().String()
+	if !merkle {
+		return txid, nil
+	}
+
+	branch, _ := s.txBranch(int32(height), int(position))
+
+	return &electrum.GetMerkleProofFromPosResult{
+		Hash:   txid,
+		Merkle: branch,
+	}, nil
+}
+
+// txBranch returns the merkle branch of the transaction at position in the block at height.
+func (s *Server) txBranch(height int32, position int) ([]string, string) {
+	txs := s.blocks[height].Transactions
+
+	hashes := make([]chainhash.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.TxHash()
+	}
+
+	branch, root := merkleBranch(hashes, position)
+
+	return hashStrings(branch), root.String()
+}
+
+func txHex(tx *wire.MsgTx) string {
+	var buf bytes.Buffer
+	tx.Serialize(&buf)
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func hashStrings(hashes []chainhash.Hash) []string {
+	result := make([]string, len(hashes))
+	for i, hash := range hashes {
+		result[i] = hash.String()
+	}
+
+	return result
+}
+
+func invalidParams(msg string) error {
+	return &electrum.APIError{Code: codeInvalidParams, Message: msg}
+}
+
+// params are the positional parameters of a request.
+type params []json.RawMessage
+
+// parseParams decodes the parameter list of a request. Named parameters are not
+// supported and treated like an empty list.
+func parseParams(raw json.RawMessage) params {
+	var p params
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil
+	}
+
+	return p
+}
+
+func (p params) raw(index int) json.RawMessage {
+	if index >= len(p) || string(p[index]) == "null" {
+		return nil
+	}
+
+	return p[index]
+}
+
+func (p params) string(index int) (string, error) {
+	var v string
+	if err := json.Unmarshal(p.raw(index), &v); err != nil {
+		return "", invalidParams(fmt.Sprintf("parameter %d: %v", index, err))
+	}
+
+	return v, nil
+}
+
+func (p params) uint(index int) (uint32, error) {
+	var v uint32
+	if err := json.Unmarshal(p.raw(index), &v); err != nil {
+		return 0, invalidParams(fmt.Sprintf("parameter %d: %v", index, err))
+	}
+
+	return v, nil
+}
+
+func (p params) optionalUint(index int) (uint32, error) {
+	if p.raw(index) == nil {
+		return 0, nil
+	}
+
+	return p.uint(index)
+}
+
+func (p params) optionalBool(index int) (bool, error) {
+	if p.raw(index) == nil {
+		return false, nil
+	}
+
+	var v bool
+	if err := json.Unmarshal(p.raw(index), &v); err != nil {
+		return false, invalidParams(fmt.Sprintf("parameter %d: %v", index, err))
+	}
+
+	return v, nil
+}
+
+func (p params) hash(index int) (chainhash.Hash, error) {
+	v, err := p.string(index)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	hash, err := chainhash.NewHashFromStr(v)
+	if err != nil || len(v) != 2*chainhash.HashSize {
+		return chainhash.Hash{}, invalidParams(fmt.Sprintf("parameter %d: invalid hash %q", index, v))
+	}
+
+	return *hash, nil
+}
+
+func (p params) scripthash(index int) (string, error) {
+	v, err := p.string(index)
+	if err != nil {
+		return "", err
+	}
+
+	if data, err := hex.DecodeString(v); err != nil || len(data) != sha256.Size {
+		return "", invalidParams(fmt.Sprintf("parameter %d: invalid scripthash %q", index, v))
+	}
+
+	return strings.ToLower(v), nil
+}
@@ -0,0 +1,321 @@
+// Package electrumtest provides an in-process Electrum server for tests. The server
+// is backed by an in-memory regtest chain the test controls: it mines blocks with
+// valid headers, adds transactions to the mempool, confirms them and triggers
+// reorgs, and the connected clients get the matching notifications.
+package electrumtest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+const (
+	// ServerVersion is the software version reported by the server.
+	ServerVersion = "electrumtest 1.0"
+
+	// ProtocolMin is the oldest protocol version the server speaks.
+	ProtocolMin = "1.4"
+
+	// ProtocolMax is the newest protocol version the server speaks.
+	ProtocolMax = "1.4.2"
+
+	// MaxHeaders is the maximum number of headers returned by blockchain.block.headers.
+	MaxHeaders = 2016
+)
+
+// Option configures optional behaviour of a Server.
+type Option func(*Server)
+
+// WithBanner sets the text returned by server.banner.
+func WithBanner(banner string) Option {
+	return func(s *Server) {
+		s.banner = banner
+	}
+}
+
+// WithFee sets the results of blockchain.estimatefee and blockchain.relayfee in
+// coin units per kilobyte.
+func WithFee(estimate, relay float64) Option {
+	return func(s *Server) {
+		s.fee = estimate
+		s.relayFee = relay
+	}
+}
+
+// Server store information about an in-process Electrum server.
+type Server struct {
+	params   *chaincfg.Params
+	banner   string
+	fee      float64
+	relayFee float64
+
+	lock     sync.Mutex
+	blocks   []*wire.MsgBlock
+	mempool  []*wire.MsgTx
+	nonce    uint64
+	sessions map[*session]struct{}
+	listener net.Listener
+	closed   bool
+}
+
+// NewServer initialize a new server with a regtest chain holding only the genesis block.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		params:   &chaincfg.RegressionNetParams,
+		banner:   "Welcome to electrumtest",
+		fee:      0.0001,
+		relayFee: 0.00001,
+		sessions: make(map[*session]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.blocks = []*wire.MsgBlock{s.params.GenesisBlock}
+
+	return s
+}
+
+// GenesisHash returns the hash of the genesis block.
+func (s *Server) GenesisHash() chainhash.Hash {
+	return *s.params.GenesisHash
+}
+
+// Listen starts to serve on a TCP port of the loopback interface and returns its address.
+func (s *Server) Listen() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	s.lock.Lock()
+	s.listener = l
+	s.lock.Unlock()
+
+	go s.Serve(l)
+
+	return l.Addr().String(), nil
+}
+
+// Serve accepts connections on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single connection until it is closed.
+func (s *Server) ServeConn(conn net.Conn) {
+	sess := newSession(conn)
+
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		conn.Close()
+		return
+	}
+	s.sessions[sess] = struct{}{}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.sessions, sess)
+		s.lock.Unlock()
+
+		sess.close()
+	}()
+
+	go sess.write()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		reply := s.handle(sess, line)
+		if reply != nil {
+			sess.send(reply)
+		}
+		s.lock.Unlock()
+	}
+}
+
+// Transport returns a transport connected to the server through an in-memory pipe.
+func (s *Server) Transport(ctx context.Context, opts ...electrum.TransportOption) *electrum.StreamTransport {
+	conn, server := net.Pipe()
+	go s.ServeConn(server)
+
+	return electrum.NewStreamTransport(ctx, conn, opts...)
+}
+
+// Client returns a client connected to the server through an in-memory pipe.
+func (s *Server) Client(ctx context.Context, opts ...electrum.ClientOption) *electrum.Client {
+	return electrum.NewClient(ctx, s.Transport(ctx), opts...)
+}
+
+// Dial returns a DialFunc opening a new in-memory connection to the server, e.g.
+// for electrum.WithReconnect.
+func (s *Server) Dial(opts ...electrum.TransportOption) electrum.DialFunc {
+	return func(ctx context.Context) (electrum.Transport, error) {
+		return s.Transport(ctx, opts...), nil
+	}
+}
+
+// Disconnect closes all open connections, the server keeps accepting new ones.
+func (s *Server) Disconnect() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for sess := range s.sessions {
+		sess.close()
+	}
+}
+
+// Close stops listening and closes all connections.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+
+	for sess := range s.sessions {
+		sess.close()
+	}
+
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+
+	return nil
+}
+
+// notify sends the header and scripthash notifications to the sessions whose
+// subscriptions changed.
+func (s *Server) notify() {
+	idx := s.index()
+
+	tip := s.blocks[s.height()].Header
+	hash := tip.BlockHash()
+
+	for sess := range s.sessions {
+		if sess.headers && sess.tip != hash {
+			sess.tip = hash
+			sess.notify("blockchain.headers.subscribe", s.headerResult(s.height()))
+		}
+
+		for scripthash, last := range sess.scripthashes {
+			status := idx.status(scripthash)
+			if equalStatus(status, last) {
+				continue
+			}
+
+			sess.scripthashes[scripthash] = status
+			sess.notify("blockchain.scripthash.subscribe", scripthash, status)
+		}
+	}
+}
+
+func equalStatus(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// session store information about a client connection.
+type session struct {
+	conn net.Conn
+
+	headers      bool
+	tip          chainhash.Hash
+	scripthashes map[string]*string
+
+	lock  sync.Mutex
+	queue [][]byte
+	wake  chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSession(conn net.Conn) *session {
+	return &session{
+		conn:         conn,
+		scripthashes: make(map[string]*string),
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+}
+
+// send queues a message. The queue is unbounded, the server never blocks on a
+// slow client.
+func (c *session) send(msg []byte) {
+	c.lock.Lock()
+	c.queue = append(c.queue, append(msg, '\n'))
+	c.lock.Unlock()
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *session) notify(method string, params ...any) {
+	msg, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+	if err != nil {
+		return
+	}
+
+	c.send(msg)
+}
+
+func (c *session) write() {
+	for {
+		c.lock.Lock()
+		queue := c.queue
+		c.queue = nil
+		c.lock.Unlock()
+
+		for _, msg := range queue {
+			if _, err := c.conn.Write(msg); err != nil {
+				c.close()
+				return
+			}
+		}
+
+		if len(queue) > 0 {
+			continue
+		}
+
+		select {
+		case <-c.wake:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *session) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
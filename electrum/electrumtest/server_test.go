@@ -0,0 +1,365 @@
+package electrumtest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+	"github.com/zauberhaus/go-electrum/electrum/electrumtest"
+)
+
+// p2wpkh returns a pay-to-witness-pubkey-hash script for a made up key hash.
+func p2wpkh(t *testing.T, b byte) []byte {
+	t.Helper()
+
+	script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(bytes.Repeat([]byte{b}, 20)).Script()
+	require.NoError(t, err)
+
+	return script
+}
+
+// merkleRoot computes the root of a merkle branch as returned by the server.
+func merkleRoot(t *testing.T, leaf chainhash.Hash, branch []string, index uint32) chainhash.Hash {
+	t.Helper()
+
+	hash := leaf
+	for _, node := range branch {
+		sibling, err := chainhash.NewHashFromStr(node)
+		require.NoError(t, err)
+
+		var pair []byte
+		if index&1 == 0 {
+			pair = append(append(pair, hash[:]...), sibling[:]...)
+		} else {
+			pair = append(append(pair, sibling[:]...), hash[:]...)
+		}
+
+		hash = chainhash.DoubleHashH(pair)
+		index >>= 1
+	}
+
+	return hash
+}
+
+func parseHeader(t *testing.T, data string) *wire.BlockHeader {
+	t.Helper()
+
+	raw, err := hex.DecodeString(data)
+	require.NoError(t, err)
+
+	var header wire.BlockHeader
+	require.NoError(t, header.Deserialize(bytes.NewReader(raw)))
+
+	return &header
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no notification")
+	}
+
+	var zero T
+	return zero
+}
+
+func TestServer_Info(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer(electrumtest.WithBanner("hello"), electrumtest.WithFee(0.0002, 0.00002))
+	defer server.Close()
+
+	client := server.Client(ctx)
+	defer client.Shutdown()
+
+	serverVer, protocolVer, err := client.ServerVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, electrumtest.ServerVersion, serverVer)
+	assert.Equal(t, electrumtest.ProtocolMax, protocolVer)
+
+	features, err := client.ServerFeatures(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, server.GenesisHash().String(), features.GenesisHash)
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", banner)
+
+	fee, err := client.GetFee(ctx, 6)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0002, fee, 1e-9)
+
+	relayFee, err := client.GetRelayFee(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00002, relayFee, 1e-9)
+
+	histogram, err := client.GetFeeHistogram(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, histogram)
+
+	require.NoError(t, client.Ping(ctx))
+	require.NoError(t, client.ServerAddPeer(ctx, features))
+
+	peers, err := client.ServerPeers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+
+	_, err = client.SubscribeMasternode(ctx, "collateral")
+	var apiErr *electrum.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, -32601, apiErr.Code)
+}
+
+func TestServer_Headers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client := server.Client(ctx)
+	defer client.Shutdown()
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), receive(t, headers).Height)
+
+	server.Mine(3)
+
+	tip := receive(t, headers)
+	assert.Equal(t, int32(3), tip.Height)
+	assert.Equal(t, server.Block(3).Header.BlockHash(), parseHeader(t, tip.Hex).BlockHash())
+
+	chunk, err := client.GetBlockHeaders(ctx, 0, 4, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(4), chunk.Count)
+	require.Len(t, chunk.Headers, 4*2*80)
+
+	// the headers link to each other
+	prev := parseHeader(t, chunk.Headers[:160])
+	assert.Equal(t, server.GenesisHash(), prev.BlockHash())
+	for i := 1; i < 4; i++ {
+		header := parseHeader(t, chunk.Headers[i*160:(i+1)*160])
+		assert.Equal(t, prev.BlockHash(), header.PrevBlock)
+		prev = header
+	}
+
+	// the branch of the last header leads to the checkpoint root
+	assert.Equal(t, chunk.Root, merkleRoot(t, prev.BlockHash(), chunk.Branch, 3).String())
+
+	header, err := client.GetBlockHeader(ctx, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, chunk.Root, header.Root)
+	assert.Equal(t, header.Root, merkleRoot(t, parseHeader(t, header.Header).BlockHash(), header.Branch, 1).String())
+
+	// the count is capped at the tip
+	chunk, err = client.GetBlockHeaders(ctx, 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), chunk.Count)
+
+	_, err = client.GetBlockHeader(ctx, 4)
+	assert.Error(t, err)
+}
+
+func TestServer_Scripthash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client := server.Client(ctx)
+	defer client.Shutdown()
+
+	script := p2wpkh(t, 1)
+	scripthash := electrumtest.ScriptHash(script)
+
+	sub, notifications := client.SubscribeScripthash()
+	require.NoError(t, sub.Add(ctx, scripthash))
+
+	// unconfirmed payment
+	funding := server.Pay(script, 100000)
+
+	status := receive(t, notifications)
+	assert.Equal(t, scripthash, status.Params[0])
+	assert.NotEmpty(t, status.Params[1])
+
+	balance, err := client.GetBalance(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Unconfirmed: 100000}, balance)
+
+	mempool, err := client.GetMempool(ctx, scripthash)
+	require.NoError(t, err)
+	require.Len(t, mempool, 1)
+	assert.Equal(t, funding.TxHash().String(), mempool[0].Hash)
+	assert.Equal(t, int32(0), mempool[0].Height)
+
+	// confirmation
+	block := server.Mine(1)[0]
+
+	confirmed := receive(t, notifications)
+	assert.NotEqual(t, status.Params[1], confirmed.Params[1])
+
+	balance, err = client.GetBalance(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 100000}, balance)
+
+	unspent, err := client.ListUnspent(ctx, scripthash)
+	require.NoError(t, err)
+	require.Len(t, unspent, 1)
+	assert.Equal(t, &electrum.ListUnspentResult{Height: 1, Hash: funding.TxHash().String(), Value: 100000}, unspent[0])
+
+	proof, err := client.GetMerkleProof(ctx, funding.TxHash().String(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, block.Header.MerkleRoot, merkleRoot(t, funding.TxHash(), proof.Merkle, proof.Position))
+
+	txid, err := client.GetHashFromPosition(ctx, 1, proof.Position)
+	require.NoError(t, err)
+	assert.Equal(t, funding.TxHash().String(), txid)
+
+	fromPos, err := client.GetMerkleProofFromPosition(ctx, 1, proof.Position)
+	require.NoError(t, err)
+	assert.Equal(t, proof.Merkle, fromPos.Merkle)
+
+	tx, err := client.GetTransaction(ctx, funding.TxHash().String())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), tx.Confirmations)
+	assert.Equal(t, block.Header.BlockHash().String(), tx.Blockhash)
+	require.Len(t, tx.Vout, 1)
+	assert.Equal(t, "witness_v0_keyhash", tx.Vout[0].ScriptPubkey.Type)
+
+	// spending by a broadcast transaction
+	fundingHash := funding.TxHash()
+
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundingHash, 0), nil, nil))
+	spend.AddTxOut(wire.NewTxOut(90000, p2wpkh(t, 2)))
+
+	var raw bytes.Buffer
+	require.NoError(t, spend.Serialize(&raw))
+
+	txid, err = client.BroadcastTransaction(ctx, hex.EncodeToString(raw.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, spend.TxHash().String(), txid)
+
+	receive(t, notifications)
+
+	balance, err = client.GetBalance(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 100000, Unconfirmed: -100000}, balance)
+
+	history, err := client.GetHistory(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, []*electrum.GetMempoolResult{
+		{Hash: funding.TxHash().String(), Height: 1},
+		{Hash: spend.TxHash().String(), Height: 0, Fee: 10000},
+	}, history)
+
+	unspent, err = client.ListUnspent(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Empty(t, unspent)
+
+	histogram, err := client.GetFeeHistogram(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, histogram)
+
+	_, err = client.BroadcastTransaction(ctx, hex.EncodeToString(raw.Bytes()))
+	assert.Error(t, err)
+
+	require.NoError(t, sub.Remove(ctx, scripthash))
+}
+
+func TestServer_Reorg(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client := server.Client(ctx)
+	defer client.Shutdown()
+
+	script := p2wpkh(t, 1)
+	scripthash := electrumtest.ScriptHash(script)
+
+	server.Mine(5)
+	tx := server.Pay(script, 5000)
+	server.Mine(1)
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	old := receive(t, headers)
+	assert.Equal(t, int32(6), old.Height)
+
+	sub, notifications := client.SubscribeScripthash()
+	require.NoError(t, sub.Add(ctx, scripthash))
+	confirmed := receive(t, notifications)
+
+	_, err = server.Reorg(2, 3)
+	require.NoError(t, err)
+
+	tip := receive(t, headers)
+	assert.Equal(t, int32(7), tip.Height)
+	assert.NotEqual(t, parseHeader(t, old.Hex).PrevBlock, server.Block(5).Header.BlockHash())
+
+	// the transaction is unconfirmed again
+	unconfirmed := receive(t, notifications)
+	assert.NotEqual(t, confirmed.Params[1], unconfirmed.Params[1])
+
+	history, err := client.GetHistory(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, []*electrum.GetMempoolResult{{Hash: tx.TxHash().String()}}, history)
+}
+
+func TestServer_Listen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	addr, err := server.Listen()
+	require.NoError(t, err)
+
+	client, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	header, err := client.GetBlockHeader(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, server.GenesisHash(), parseHeader(t, header.Header).BlockHash())
+}
+
+func TestServer_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client := electrum.NewClient(ctx, server.Transport(ctx), electrum.WithReconnect(server.Dial()))
+	defer client.Shutdown()
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	receive(t, headers)
+
+	server.Disconnect()
+	server.Mine(1)
+
+	// the subscription is renewed on the new connection
+	assert.Equal(t, int32(1), receive(t, headers).Height)
+}
@@ -0,0 +1,26 @@
+package electrum
+
+import (
+	"context"
+	"time"
+)
+
+// WriteQueue gives the tests access to the write queue of the stream transports.
+type WriteQueue struct {
+	q *writeQueue
+}
+
+// NewWriteQueue starts a write queue passing the coalesced messages to flush.
+func NewWriteQueue(done <-chan struct{}, flush func(deadline time.Time, bodies [][]byte) (int, error)) *WriteQueue {
+	return &WriteQueue{q: newWriteQueue(0, 0, done, flush, func(error) {})}
+}
+
+// Send queues body and waits until it has been flushed.
+func (w *WriteQueue) Send(ctx context.Context, body []byte) error {
+	return w.q.send(ctx, body)
+}
+
+// Queued returns the number of messages waiting for the writer.
+func (w *WriteQueue) Queued() int {
+	return len(w.q.queue)
+}
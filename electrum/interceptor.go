@@ -0,0 +1,108 @@
+package electrum
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Invoker sends a request for method with params and returns the raw result of
+// the response.
+type Invoker func(ctx context.Context, method string, params []any) (json.RawMessage, error)
+
+// Interceptor wraps every call of a client. It may change method and params, call
+// next any number of times, change the result or the error or answer without
+// calling next at all.
+type Interceptor func(ctx context.Context, method string, params []any, next Invoker) (json.RawMessage, error)
+
+// BatchRequest is a single call of a batch.
+type BatchRequest struct {
+	Method string
+	Params []any
+}
+
+// BatchResult is the raw result or the error of a single call of a batch.
+type BatchResult struct {
+	Result json.RawMessage
+	Err    error
+}
+
+// BatchInvoker sends the requests of a batch in one message and returns a result
+// for every request in the same order. The error is only set if the batch could
+// not be completed, the unanswered requests carry it as well.
+type BatchInvoker func(ctx context.Context, requests []BatchRequest) ([]BatchResult, error)
+
+// BatchInterceptor wraps every batch sent by a client. It may change the requests,
+// call next any number of times, change the results or answer without calling next
+// at all, but must return a result for every request it got.
+type BatchInterceptor func(ctx context.Context, requests []BatchRequest, next BatchInvoker) ([]BatchResult, error)
+
+// NotificationHandler delivers a notification to the subscriptions of method.
+type NotificationHandler func(method string, params json.RawMessage)
+
+// NotificationInterceptor wraps the delivery of every notification pushed by the
+// server. It may change the notification or drop it by not calling next.
+type NotificationInterceptor func(method string, params json.RawMessage, next NotificationHandler)
+
+// WithInterceptors adds interceptors to the calls of the client. The first
+// interceptor is the outermost one. The calls of a Batch are sent as a single
+// message and pass the batch interceptors instead, see WithBatchInterceptors.
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(c *clientConfig) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// WithBatchInterceptors adds interceptors to the batches sent by the client. The
+// first interceptor is the outermost one.
+func WithBatchInterceptors(interceptors ...BatchInterceptor) ClientOption {
+	return func(c *clientConfig) {
+		c.batchInterceptors = append(c.batchInterceptors, interceptors...)
+	}
+}
+
+// WithNotificationInterceptors adds interceptors to the notifications received by
+// the client. The first interceptor is the outermost one.
+func WithNotificationInterceptors(interceptors ...NotificationInterceptor) ClientOption {
+	return func(c *clientConfig) {
+		c.notificationInterceptors = append(c.notificationInterceptors, interceptors...)
+	}
+}
+
+// chainInterceptors wraps invoker into interceptors.
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+
+		invoker = func(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+			return interceptor(ctx, method, params, next)
+		}
+	}
+
+	return invoker
+}
+
+// chainBatchInterceptors wraps invoker into interceptors.
+func chainBatchInterceptors(interceptors []BatchInterceptor, invoker BatchInvoker) BatchInvoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+
+		invoker = func(ctx context.Context, requests []BatchRequest) ([]BatchResult, error) {
+			return interceptor(ctx, requests, next)
+		}
+	}
+
+	return invoker
+}
+
+// chainNotificationInterceptors wraps handler into interceptors.
+func chainNotificationInterceptors(interceptors []NotificationInterceptor, handler NotificationHandler) NotificationHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+
+		handler = func(method string, params json.RawMessage) {
+			interceptor(method, params, next)
+		}
+	}
+
+	return handler
+}
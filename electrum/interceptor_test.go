@@ -0,0 +1,271 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+	"github.com/zauberhaus/go-electrum/electrum/electrumtest"
+)
+
+func TestInterceptors_Order(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		return method
+	})
+
+	var calls []string
+	record := func(name string) electrum.Interceptor {
+		return func(ctx context.Context, method string, params []any, next electrum.Invoker) (json.RawMessage, error) {
+			calls = append(calls, name+" "+method)
+			result, err := next(ctx, method, params)
+			calls = append(calls, name+" "+string(result))
+
+			return result, err
+		}
+	}
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake(), electrum.WithInterceptors(record("outer"), record("inner")))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "server.banner", banner)
+
+	assert.Equal(t, []string{
+		"outer server.banner",
+		"inner server.banner",
+		`inner "server.banner"`,
+		`outer "server.banner"`,
+	}, calls)
+}
+
+func TestInterceptors_ShortCircuit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		requests.Add(1)
+		return method
+	})
+
+	errInjected := errors.New("injected")
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake(), electrum.WithInterceptors(
+		func(ctx context.Context, method string, params []any, next electrum.Invoker) (json.RawMessage, error) {
+			switch method {
+			case "server.banner":
+				return json.RawMessage(`"cached"`), nil
+			case "server.donation_address":
+				return nil, errInjected
+			}
+
+			return next(ctx, method, params)
+		},
+	))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", banner)
+
+	_, err = client.ServerDonation(ctx)
+	assert.ErrorIs(t, err, errInjected)
+
+	fee, err := client.GetRelayFee(ctx)
+	assert.Error(t, err) // "blockchain.relayfee" is not a number
+	assert.Equal(t, float32(-1), fee)
+
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestInterceptors_Retry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		requests.Add(1)
+		return method
+	})
+
+	retry := func(ctx context.Context, method string, params []any, next electrum.Invoker) (json.RawMessage, error) {
+		for {
+			result, err := next(ctx, method, params)
+			if err == nil || ctx.Err() != nil {
+				return result, err
+			}
+		}
+	}
+
+	var failures atomic.Int32
+	flaky := func(ctx context.Context, method string, params []any, next electrum.Invoker) (json.RawMessage, error) {
+		if failures.Add(1) <= 2 {
+			return nil, electrum.ErrConnectionLost
+		}
+
+		return next(ctx, method, params)
+	}
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake(), electrum.WithInterceptors(retry, flaky))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "server.banner", banner)
+
+	assert.Equal(t, int32(3), failures.Load())
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestBatchInterceptors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	var sent []string
+	record := func(ctx context.Context, requests []electrum.BatchRequest, next electrum.BatchInvoker) ([]electrum.BatchResult, error) {
+		for _, r := range requests {
+			sent = append(sent, r.Method)
+		}
+
+		return next(ctx, requests)
+	}
+
+	// answers fee estimates from a cache and sends the rest
+	cache := func(ctx context.Context, requests []electrum.BatchRequest, next electrum.BatchInvoker) ([]electrum.BatchResult, error) {
+		results := make([]electrum.BatchResult, len(requests))
+
+		var forward []electrum.BatchRequest
+		var index []int
+		for i, r := range requests {
+			if r.Method == "blockchain.estimatefee" {
+				results[i].Result = json.RawMessage("0.5")
+				continue
+			}
+
+			forward = append(forward, r)
+			index = append(index, i)
+		}
+
+		answers, err := next(ctx, forward)
+		for i, answer := range answers {
+			results[index[i]] = answer
+		}
+
+		return results, err
+	}
+
+	var calls atomic.Int32
+	client := server.Client(ctx, electrum.WithBatchInterceptors(record, cache), electrum.WithInterceptors(
+		func(ctx context.Context, method string, params []any, next electrum.Invoker) (json.RawMessage, error) {
+			if method != "server.version" {
+				calls.Add(1)
+			}
+
+			return next(ctx, method, params)
+		},
+	))
+	defer client.Shutdown()
+
+	b := client.NewBatch()
+	fee := b.GetFee(6)
+	banner := electrum.BatchAdd[string](b, "server.banner")
+
+	require.NoError(t, b.Send(ctx))
+
+	f, err := fee.Result()
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, f, 1e-9)
+
+	text, err := banner.Result()
+	require.NoError(t, err)
+	assert.NotEmpty(t, text)
+
+	assert.Equal(t, []string{"blockchain.estimatefee", "server.banner"}, sent)
+	assert.Zero(t, calls.Load(), "batch passed the call interceptors")
+}
+
+func TestBatchInterceptors_MissingResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client := server.Client(ctx, electrum.WithBatchInterceptors(
+		func(ctx context.Context, requests []electrum.BatchRequest, next electrum.BatchInvoker) ([]electrum.BatchResult, error) {
+			return nil, nil
+		},
+	))
+	defer client.Shutdown()
+
+	b := client.NewBatch()
+	banner := electrum.BatchAdd[string](b, "server.banner")
+
+	require.Error(t, b.Send(ctx))
+
+	_, err := banner.Result()
+	assert.Error(t, err)
+}
+
+func TestNotificationInterceptors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	var seen atomic.Int32
+	client := server.Client(ctx, electrum.WithNotificationInterceptors(
+		func(method string, params json.RawMessage, next electrum.NotificationHandler) {
+			seen.Add(1)
+			next(method, params)
+		},
+		func(method string, params json.RawMessage, next electrum.NotificationHandler) {
+			var headers []*electrum.SubscribeHeadersResult
+			require.NoError(t, json.Unmarshal(params, &headers))
+
+			// drop odd heights and mark the others
+			if headers[0].Height%2 == 1 {
+				return
+			}
+
+			headers[0].Hex = "intercepted"
+
+			params, _ = json.Marshal(headers)
+			next(method, params)
+		},
+	))
+	defer client.Shutdown()
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), (<-headers).Height)
+
+	server.Mine(1)
+	server.Mine(1)
+
+	select {
+	case header := <-headers:
+		assert.Equal(t, &electrum.SubscribeHeadersResult{Height: 2, Hex: "intercepted"}, header)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no notification")
+	}
+
+	assert.Equal(t, int32(2), seen.Load())
+}
@@ -0,0 +1,65 @@
+package electrum
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultKeepAliveFailures is the number of timed out requests in a row after which
+// the connection is considered dead unless WithKeepAlive sets another limit.
+const DefaultKeepAliveFailures = 3
+
+// keepAlive pings the server periodically and drops the connection after too
+// many failed pings or timed out requests in a row.
+func (s *Client) keepAlive() {
+	ticker := time.NewTicker(s.config.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+
+		generation := s.generation.Load()
+
+		ctx, cancel := context.WithTimeout(s.ctx, s.config.keepAliveInterval)
+		start := time.Now()
+		err := s.Ping(ctx)
+		cancel()
+
+		if err == nil {
+			s.failures.Store(0)
+			s.latency.Store(int64(time.Since(start)))
+			continue
+		}
+
+		s.failed(generation, err)
+	}
+}
+
+// failed counts a failed keepalive ping or a timed out request on the connection
+// of generation. A response resets the count, too many failures in a row drop the
+// connection, also without keepalive.
+func (s *Client) failed(generation uint64, err error) {
+	limit := s.config.keepAliveFailures
+	if s.IsShutdown() || s.generation.Load() != generation {
+		return
+	}
+
+	failures := int(s.failures.Add(1))
+	s.log.Warnf("Health check %d/%d failed: %v", failures, limit, err)
+
+	if failures >= limit {
+		s.failures.Store(0)
+		s.drop(fmt.Errorf("%w: %w", ErrUnresponsive, err))
+	}
+}
+
+// Latency returns the round-trip time of the last successful keepalive ping or
+// zero if none has been measured yet.
+func (s *Client) Latency() time.Duration {
+	return time.Duration(s.latency.Load())
+}
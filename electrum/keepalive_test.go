@@ -0,0 +1,98 @@
+package electrum_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestKeepAlive_Latency(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	stop := startAutoResponder(transport, nil)
+	defer stop()
+
+	client := electrum.NewClient(ctx, transport, electrum.WithKeepAlive(10*time.Millisecond, 2))
+	defer client.Shutdown()
+
+	require.Eventually(t, func() bool {
+		return client.Latency() > 0
+	}, 2*time.Second, 5*time.Millisecond)
+
+	assert.False(t, client.IsShutdown())
+}
+
+func TestKeepAlive_Shutdown(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithKeepAlive(10*time.Millisecond, 2))
+
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("silent server was not detected")
+	}
+
+	assert.ErrorIs(t, client.Err(), electrum.ErrUnresponsive)
+}
+
+func TestKeepAlive_Reconnect(t *testing.T) {
+	ctx := context.Background()
+
+	first := NewMockTransport()
+	second := NewMockTransport()
+
+	client := electrum.NewClient(ctx, first,
+		electrum.WithKeepAlive(10*time.Millisecond, 2),
+		electrum.WithReconnect(dialSequence(second)),
+		electrum.WithBackoff(testBackoff),
+	)
+	defer client.Shutdown()
+
+	method := respond(t, second, func(method string) any {
+		return [2]string{"ElectrumX 1.16.0", "1.4"}
+	})
+
+	assert.Equal(t, "server.version", method)
+	assert.False(t, client.IsShutdown())
+}
+
+func TestTCPTransport_ReadTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// never answer
+		time.Sleep(5 * time.Second)
+	}()
+
+	transport, err := electrum.NewTCPTransport(ctx, l.Addr().String(), electrum.WithReadTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	defer transport.Close()
+
+	select {
+	case err := <-transport.Errors():
+		var netErr net.Error
+		require.True(t, errors.As(err, &netErr))
+		assert.True(t, netErr.Timeout())
+	case <-time.After(5 * time.Second):
+		t.Fatal("read timeout did not fire")
+	}
+}
@@ -0,0 +1,203 @@
+package electrum
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// DefaultMaxMessageSize is the maximum size of a message a transport accepts by default.
+const DefaultMaxMessageSize = 16 << 20
+
+// keep this many bytes of the start and the end of a discarded message to find its id
+const idWindow = 256
+
+// ErrMessageTooLarge throws an error if a message exceeds the configured size limit.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// MessageTooLargeError is returned if a message exceeds a size limit. If the id of an
+// oversized response could be recovered only the matching request fails, otherwise
+// the connection is dropped.
+type MessageTooLargeError struct {
+	Size   int    // size of the message in bytes, 0 if unknown
+	Limit  int    // limit which was exceeded
+	ID     uint64 // id of the response, 0 if unknown
+	Method string // method of the request, set for per-method limits
+}
+
+func (e *MessageTooLargeError) Error() string {
+	msg := fmt.Sprintf("%s: exceeds the limit of %d bytes", ErrMessageTooLarge, e.Limit)
+	if e.Size > 0 {
+		msg = fmt.Sprintf("%s: %d bytes exceed the limit of %d bytes", ErrMessageTooLarge, e.Size, e.Limit)
+	}
+	if e.Method != "" {
+		msg += " for " + e.Method
+	}
+
+	return msg
+}
+
+func (e *MessageTooLargeError) Unwrap() error {
+	return ErrMessageTooLarge
+}
+
+func (e *MessageTooLargeError) requestID() uint64 {
+	return e.ID
+}
+
+// WithMaxMessageSize limits the size of a single message received by a transport.
+// A value <= 0 restores DefaultMaxMessageSize. It is the only limit bounding the
+// memory used for a message, larger messages are skipped while they are read.
+func WithMaxMessageSize(n int) TransportOption {
+	return func(c *transportConfig) {
+		c.maxMessageSize = n
+	}
+}
+
+// WithResponseLimit limits the size of the responses to method. A larger response
+// fails the request with a MessageTooLargeError before it gets decoded. The limit
+// is checked after the response has been received, as the transport only knows the
+// request of a message once it has been read completely. It saves the decoding but
+// does not bound memory, use WithMaxMessageSize for that.
+func WithResponseLimit(method string, n int) ClientOption {
+	return func(c *clientConfig) {
+		if c.responseLimits == nil {
+			c.responseLimits = make(map[string]int)
+		}
+
+		c.responseLimits[method] = n
+	}
+}
+
+// limitResponse replaces resp with a MessageTooLargeError if it exceeds the limit for
+// method. The response is already in memory, only WithMaxMessageSize limits that.
+func (s *Client) limitResponse(method string, resp *container) *container {
+	limit, ok := s.config.responseLimits[method]
+	if !ok || limit <= 0 || resp.err != nil || len(resp.content) <= limit {
+		return resp
+	}
+
+	return &container{
+		err: &MessageTooLargeError{
+			Size:   len(resp.content),
+			Limit:  limit,
+			Method: method,
+		},
+	}
+}
+
+// requestError is a transport error belonging to a single request, e.g. an
+// oversized response. The id is 0 if unknown.
+type requestError interface {
+	error
+	requestID() uint64
+}
+
+// failRequest fails the request a transport error belongs to. It reports whether
+// err has been handled without dropping the connection.
+func (s *Client) failRequest(err error) bool {
+	var reqErr requestError
+	if !errors.As(err, &reqErr) || reqErr.requestID() == 0 {
+		return false
+	}
+
+	c, ok := Get(s.handlers, func(val map[uint64]chan *container) (chan *container, bool) {
+		c, ok := val[reqErr.requestID()]
+		return c, ok && c != nil
+	})
+
+	if !ok {
+		return false
+	}
+
+	select {
+	case c <- &container{err: err}:
+	default:
+	}
+
+	return true
+}
+
+// readLine reads the next newline terminated message from r. A message larger than
+// limit is discarded without buffering it and a *MessageTooLargeError is returned.
+// The reader stays usable in that case.
+func readLine(r *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+
+	for {
+		frag, err := r.ReadSlice(nl)
+
+		if limit > 0 && len(line)+len(frag) > limit {
+			return nil, discardLine(r, line, frag, err, limit)
+		}
+
+		line = append(line, frag...)
+
+		if err == nil {
+			return line, nil
+		}
+
+		if err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+}
+
+// discardLine skips the rest of an oversized message and recovers its id from the
+// beginning or the end of it.
+func discardLine(r *bufio.Reader, line []byte, frag []byte, err error, limit int) error {
+	size := len(line) + len(frag)
+
+	head := append(line, frag...)
+	head = head[:min(len(head), idWindow)]
+
+	tail := keepTail(nil, frag)
+
+	for err == bufio.ErrBufferFull {
+		frag, err = r.ReadSlice(nl)
+		size += len(frag)
+		tail = keepTail(tail, frag)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return &MessageTooLargeError{
+		Size:  size,
+		Limit: limit,
+		ID:    responseID(head, tail),
+	}
+}
+
+func keepTail(tail []byte, frag []byte) []byte {
+	tail = append(tail, frag...)
+	if len(tail) > idWindow {
+		tail = append(tail[:0], tail[len(tail)-idWindow:]...)
+	}
+
+	return tail
+}
+
+var (
+	// servers like Fulcrum put the id in front of the result
+	headID = regexp.MustCompile(`^\s*\{\s*(?:"jsonrpc"\s*:\s*"2\.0"\s*,\s*)?"id"\s*:\s*(\d+)`)
+	// servers like ElectrumX put the id behind the result
+	tailID = regexp.MustCompile(`"id"\s*:\s*(\d+)\s*\}\s*$`)
+)
+
+func responseID(head []byte, tail []byte) uint64 {
+	for _, m := range [][][]byte{headID.FindSubmatch(head), tailID.FindSubmatch(tail)} {
+		if m == nil {
+			continue
+		}
+
+		if id, err := strconv.ParseUint(string(m[1]), 10, 64); err == nil {
+			return id
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,173 @@
+package electrum_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestMaxMessageSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		if method == "server.banner" {
+			return strings.Repeat("x", 64<<10)
+		}
+
+		return nil
+	})
+
+	client, err := electrum.NewClientTCP(ctx, addr,
+		electrum.WithTransportOptions(electrum.WithMaxMessageSize(1024)))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, err = client.ServerBanner(ctx)
+	require.ErrorIs(t, err, electrum.ErrMessageTooLarge)
+
+	var tooLarge *electrum.MessageTooLargeError
+	require.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, 1024, tooLarge.Limit)
+	assert.Greater(t, tooLarge.Size, 64<<10)
+	assert.NotZero(t, tooLarge.ID)
+
+	// only the request failed, the connection is still usable
+	require.NoError(t, client.Ping(ctx))
+	assert.False(t, client.IsShutdown())
+}
+
+func TestMaxMessageSize_IDAtEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+
+			var req struct {
+				ID     uint64 `json:"id"`
+				Method string `json:"method"`
+			}
+			if err := json.Unmarshal(line, &req); err != nil {
+				return
+			}
+
+			result := "null"
+			switch req.Method {
+			case "server.version":
+				result = `["Fake 1.0", "` + electrum.ProtocolMax + `"]`
+			case "server.banner":
+				result = `"` + strings.Repeat("x", 64<<10) + `"`
+			}
+
+			// ElectrumX sends the id behind the result
+			fmt.Fprintf(conn, `{"jsonrpc": "2.0", "result": %s, "id": %d}`+"\n", result, req.ID)
+		}
+	}()
+
+	client, err := electrum.NewClientTCP(ctx, l.Addr().String(),
+		electrum.WithTransportOptions(electrum.WithMaxMessageSize(1024)))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, err = client.ServerBanner(ctx)
+	require.ErrorIs(t, err, electrum.ErrMessageTooLarge)
+
+	require.NoError(t, client.Ping(ctx))
+}
+
+func TestMaxMessageSize_UnknownID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// an oversized notification can not be assigned to a request
+		fmt.Fprintf(conn, `{"jsonrpc":"2.0","method":"x","params":["%s"]}`+"\n", strings.Repeat("x", 4096))
+		time.Sleep(time.Second)
+	}()
+
+	client, err := electrum.NewClientTCP(ctx, l.Addr().String(),
+		electrum.WithTransportOptions(electrum.WithMaxMessageSize(1024)))
+	require.NoError(t, err)
+
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client was not shut down")
+	}
+
+	assert.ErrorIs(t, client.Err(), electrum.ErrMessageTooLarge)
+}
+
+func TestResponseLimit(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	stop := startAutoResponder(transport, strings.Repeat("x", 100))
+	defer stop()
+
+	client := electrum.NewClient(ctx, transport, electrum.WithResponseLimit("server.banner", 50))
+	defer client.Shutdown()
+
+	_, err := client.ServerBanner(ctx)
+
+	var tooLarge *electrum.MessageTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	assert.Equal(t, "server.banner", tooLarge.Method)
+	assert.Equal(t, 50, tooLarge.Limit)
+
+	_, err = client.ServerDonation(ctx)
+	assert.NoError(t, err)
+}
+
+func TestWebSocketTransport_MaxMessageSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := newWebSocketServer(t, strings.Repeat("x", 4096))
+
+	client, err := electrum.NewClientWS(ctx, strings.TrimPrefix(srv.URL, "http://"),
+		electrum.WithTransportOptions(electrum.WithMaxMessageSize(1024)))
+	require.NoError(t, err)
+
+	_, err = client.ServerBanner(ctx)
+	require.Error(t, err)
+
+	<-client.Done()
+	assert.ErrorIs(t, client.Err(), electrum.ErrMessageTooLarge)
+}
@@ -0,0 +1,248 @@
+// Package metrics exports the events and stats of Electrum clients as Prometheus
+// metrics. Register a Collector, pass the Observer of every server to the client
+// with electrum.WithObserver and Watch the client to export its stats.
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// Option configures optional behaviour of a Collector.
+type Option func(*config)
+
+type config struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace sets the prefix of all metric names, the default is "electrum".
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of the request duration histogram in seconds.
+func WithBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// Collector store information about the metrics of Electrum clients. All metrics
+// are labeled with the server name passed to Observer and Watch.
+type Collector struct {
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	inFlight      *prometheus.GaugeVec
+	notifications *prometheus.CounterVec
+	messages      *prometheus.CounterVec
+	bytes         *prometheus.CounterVec
+	connections   *prometheus.CounterVec
+	connected     *prometheus.GaugeVec
+
+	pending       *prometheus.Desc
+	subscriptions *prometheus.Desc
+	reconnects    *prometheus.Desc
+	age           *prometheus.Desc
+	latency       *prometheus.Desc
+
+	lock    sync.Mutex
+	clients map[string]*electrum.Client
+}
+
+// NewCollector initialize a new collector.
+func NewCollector(opts ...Option) *Collector {
+	cfg := config{
+		namespace: "electrum",
+		buckets:   prometheus.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ns := cfg.namespace
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "requests_total",
+			Help:      "Number of finished requests by method and result.",
+		}, []string{"server", "method", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the requests by method.",
+			Buckets:   cfg.buckets,
+		}, []string{"server", "method"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Name:      "requests_in_flight",
+			Help:      "Number of requests waiting for a response.",
+		}, []string{"server"}),
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "notifications_total",
+			Help:      "Number of notifications received by method.",
+		}, []string{"server", "method"}),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "messages_total",
+			Help:      "Number of messages sent and received.",
+		}, []string{"server", "direction"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "bytes_total",
+			Help:      "Number of bytes sent and received.",
+		}, []string{"server", "direction"}),
+		connections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "connection_events_total",
+			Help:      "Number of connects, disconnects and connection errors.",
+		}, []string{"server", "event"}),
+		connected: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Name:      "connected",
+			Help:      "Whether the transport is connected.",
+		}, []string{"server"}),
+
+		pending: prometheus.NewDesc(prometheus.BuildFQName(ns, "", "pending_requests"),
+			"Number of registered response handlers.", []string{"server"}, nil),
+		subscriptions: prometheus.NewDesc(prometheus.BuildFQName(ns, "", "subscriptions"),
+			"Number of active subscriptions.", []string{"server"}, nil),
+		reconnects: prometheus.NewDesc(prometheus.BuildFQName(ns, "", "reconnects_total"),
+			"Number of replaced connections.", []string{"server"}, nil),
+		age: prometheus.NewDesc(prometheus.BuildFQName(ns, "", "connection_age_seconds"),
+			"Age of the current connection.", []string{"server"}, nil),
+		latency: prometheus.NewDesc(prometheus.BuildFQName(ns, "", "latency_seconds"),
+			"Round-trip time of the last keepalive ping.", []string{"server"}, nil),
+
+		clients: make(map[string]*electrum.Client),
+	}
+}
+
+// Observer returns an observer recording the events of server.
+func (c *Collector) Observer(server string) electrum.Observer {
+	return &observer{
+		collector: c,
+		server:    server,
+	}
+}
+
+// Watch exports the stats of client labeled as server on every scrape.
+func (c *Collector) Watch(server string, client *electrum.Client) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.clients[server] = client
+}
+
+// Unwatch stops exporting the stats of server.
+func (c *Collector) Unwatch(server string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.clients, server)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.inFlight.Describe(ch)
+	c.notifications.Describe(ch)
+	c.messages.Describe(ch)
+	c.bytes.Describe(ch)
+	c.connections.Describe(ch)
+	c.connected.Describe(ch)
+
+	ch <- c.pending
+	ch <- c.subscriptions
+	ch <- c.reconnects
+	ch <- c.age
+	ch <- c.latency
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.inFlight.Collect(ch)
+	c.notifications.Collect(ch)
+	c.messages.Collect(ch)
+	c.bytes.Collect(ch)
+	c.connections.Collect(ch)
+	c.connected.Collect(ch)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for server, client := range c.clients {
+		if client.IsShutdown() {
+			continue
+		}
+
+		stats := client.Stats()
+
+		ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(stats.Pending), server)
+		ch <- prometheus.MustNewConstMetric(c.subscriptions, prometheus.GaugeValue, float64(stats.Subscriptions), server)
+		ch <- prometheus.MustNewConstMetric(c.reconnects, prometheus.CounterValue, float64(stats.Reconnects), server)
+		ch <- prometheus.MustNewConstMetric(c.age, prometheus.GaugeValue, stats.ConnectionAge.Seconds(), server)
+		ch <- prometheus.MustNewConstMetric(c.latency, prometheus.GaugeValue, stats.Latency.Seconds(), server)
+	}
+}
+
+// observer records the events of a single server.
+type observer struct {
+	collector *Collector
+	server    string
+}
+
+func (o *observer) Connected(string) {
+	o.collector.connections.WithLabelValues(o.server, "connected").Inc()
+	o.collector.connected.WithLabelValues(o.server).Set(1)
+}
+
+func (o *observer) Disconnected(err error) {
+	event := "disconnected"
+	if err != nil {
+		event = "failed"
+	}
+
+	o.collector.connections.WithLabelValues(o.server, event).Inc()
+	o.collector.connected.WithLabelValues(o.server).Set(0)
+}
+
+func (o *observer) MessageSent(size int) {
+	o.collector.messages.WithLabelValues(o.server, "sent").Inc()
+	o.collector.bytes.WithLabelValues(o.server, "sent").Add(float64(size))
+}
+
+func (o *observer) MessageReceived(size int) {
+	o.collector.messages.WithLabelValues(o.server, "received").Inc()
+	o.collector.bytes.WithLabelValues(o.server, "received").Add(float64(size))
+}
+
+func (o *observer) RequestStarted(string) {
+	o.collector.inFlight.WithLabelValues(o.server).Inc()
+}
+
+func (o *observer) RequestFinished(method string, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	o.collector.inFlight.WithLabelValues(o.server).Dec()
+	o.collector.requests.WithLabelValues(o.server, method, result).Inc()
+	o.collector.duration.WithLabelValues(o.server, method).Observe(duration.Seconds())
+}
+
+func (o *observer) NotificationReceived(method string) {
+	o.collector.notifications.WithLabelValues(o.server, method).Inc()
+}
@@ -0,0 +1,73 @@
+package metrics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+	"github.com/zauberhaus/go-electrum/electrum/electrumtest"
+	"github.com/zauberhaus/go-electrum/electrum/metrics"
+)
+
+func TestCollector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	addr, err := server.Listen()
+	require.NoError(t, err)
+
+	collector := metrics.NewCollector()
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake(), electrum.WithObserver(collector.Observer("test")))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	collector.Watch("test", client)
+
+	_, err = client.ServerBanner(ctx)
+	require.NoError(t, err)
+
+	_, err = client.GetTransaction(ctx, strings.Repeat("00", 32))
+	require.Error(t, err)
+
+	expected := `
+# HELP electrum_connected Whether the transport is connected.
+# TYPE electrum_connected gauge
+electrum_connected{server="test"} 1
+# HELP electrum_pending_requests Number of registered response handlers.
+# TYPE electrum_pending_requests gauge
+electrum_pending_requests{server="test"} 0
+# HELP electrum_requests_in_flight Number of requests waiting for a response.
+# TYPE electrum_requests_in_flight gauge
+electrum_requests_in_flight{server="test"} 0
+# HELP electrum_requests_total Number of finished requests by method and result.
+# TYPE electrum_requests_total counter
+electrum_requests_total{method="blockchain.transaction.get",result="error",server="test"} 1
+electrum_requests_total{method="server.banner",result="ok",server="test"} 1
+`
+
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"electrum_connected", "electrum_pending_requests", "electrum_requests_in_flight", "electrum_requests_total")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(collector, "electrum_request_duration_seconds"))
+	assert.Equal(t, 2, testutil.CollectAndCount(collector, "electrum_messages_total"))
+
+	lint, err := testutil.GatherAndLint(registry)
+	require.NoError(t, err)
+	assert.Empty(t, lint)
+
+	collector.Unwatch("test")
+	assert.Equal(t, 0, testutil.CollectAndCount(collector, "electrum_pending_requests"))
+}
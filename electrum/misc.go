@@ -26,9 +26,18 @@ func (s *Client) GetFee(ctx context.Context, target uint32) (float32, error) {
 }
 
 // GetRelayFee returns the minimum fee a transaction must pay to be accepted into the
-// remote server memory pool.
+// remote server memory pool. Since protocol 1.6 it is taken from GetMempoolInfo.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-relayfee
 func (s *Client) GetRelayFee(ctx context.Context) (float32, error) {
+	if s.protocolAtLeast("1.6") {
+		info, err := s.GetMempoolInfo(ctx)
+		if err != nil {
+			return -1, err
+		}
+
+		return float32(info.MinRelayTxFee), nil
+	}
+
 	var resp GetFeeResp
 
 	err := s.request(ctx, "blockchain.relayfee", []interface{}{}, &resp)
@@ -39,6 +48,33 @@ func (s *Client) GetRelayFee(ctx context.Context) (float32, error) {
 	return resp.Result, err
 }
 
+// GetMempoolInfoResp represents the response to GetMempoolInfo().
+type GetMempoolInfoResp struct {
+	Result *GetMempoolInfoResult `json:"result"`
+}
+
+// GetMempoolInfoResult represents the content of the result field in the response to GetMempoolInfo().
+// The fees are in coin units per kilobyte.
+type GetMempoolInfoResult struct {
+	MempoolMinFee       float64 `json:"mempoolminfee"`
+	MinRelayTxFee       float64 `json:"minrelaytxfee"`
+	IncrementalRelayFee float64 `json:"incrementalrelayfee"`
+}
+
+// GetMempoolInfo returns the fee limits of the remote server memory pool. It requires
+// protocol 1.6.
+// https://electrum-protocol.readthedocs.io/en/latest/protocol-methods.html#mempool-get-info
+func (s *Client) GetMempoolInfo(ctx context.Context) (*GetMempoolInfoResult, error) {
+	var resp GetMempoolInfoResp
+
+	err := s.request(ctx, "mempool.get_info", []interface{}{}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
 // GetFeeHistogramResp represents the response to GetFee().
 type GetFeeHistogramResp struct {
 	Result [][2]uint64 `json:"result"`
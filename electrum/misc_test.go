@@ -88,6 +88,19 @@ func (m *MockTransport) cmd(id int, result any) error {
 	return nil
 }
 
+// fail reports err on the errors channel, as a broken connection would.
+func (m *MockTransport) fail(err error) {
+	c, _ := electrum.Get(m.a, func(val electrum.Transport) (chan error, bool) {
+		if t, ok := val.(*mockTransport); ok {
+			return t.errors, true
+		}
+
+		return nil, false
+	})
+
+	c <- err
+}
+
 func (m *MockTransport) responses() chan []byte {
 	r, _ := electrum.Get(m.a, func(val electrum.Transport) (chan []byte, bool) {
 		if t, ok := val.(*mockTransport); ok {
@@ -157,7 +170,7 @@ func (t *mockTransport) Close() error {
 // newTestClient creates a new Client with a mockTransport for testing.
 func newTestClient(ctx context.Context, t *testing.T) (*electrum.Client, *MockTransport) {
 	transport := NewMockTransport()
-	client := electrum.NewClient(ctx, transport)
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake())
 
 	// Wait for the listener to start
 	time.Sleep(100 * time.Millisecond)
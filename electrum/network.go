@@ -9,8 +9,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/zauberhaus/logger"
 )
@@ -19,7 +22,7 @@ const (
 	// ClientVersion identifies the client version/name to the remote server
 	ClientVersion = "go-electrum1.1"
 
-	// ProtocolVersion identifies the support protocol version to the remote server
+	// ProtocolVersion identifies the oldest protocol version supported by the client
 	ProtocolVersion = "1.4"
 
 	nl = byte('\n')
@@ -32,6 +35,12 @@ var (
 	// ErrServerShutdown throws an error if remote server has shutdown.
 	ErrServerShutdown = errors.New("server has shutdown")
 
+	// ErrConnectionLost throws an error if the connection broke while a request was in flight.
+	ErrConnectionLost = errors.New("connection lost")
+
+	// ErrUnresponsive throws an error if the server stopped answering keepalive pings.
+	ErrUnresponsive = errors.New("server is not responding")
+
 	// ErrTimeout throws an error if request has timed out
 	ErrTimeout = errors.New("request timeout")
 
@@ -40,6 +49,13 @@ var (
 
 	// ErrDeprecated throws an error if this RPC call is deprecated.
 	ErrDeprecated = errors.New("RPC call has been deprecated")
+
+	// ErrProtocolMismatch throws an error if the negotiated protocol version is outside
+	// the requested range.
+	ErrProtocolMismatch = errors.New("protocol version mismatch")
+
+	// ErrHandshakeFailed throws an error if the server.version handshake of a new connection failed.
+	ErrHandshakeFailed = errors.New("protocol handshake failed")
 )
 
 // Transport provides interface to server transport.
@@ -57,53 +73,114 @@ type container struct {
 
 // Client stores information about the remote server.
 type Client struct {
-	transport    *Atomic[Transport]
-	handlers     *Atomic[map[uint64]chan *container]
-	pushHandlers *Atomic[map[string][]chan *container]
-
+	transport     *Atomic[Transport]
+	handlers      *Atomic[map[uint64]chan *container]
+	pushHandlers  *Atomic[map[string][]chan *container]
+	subscriptions *Atomic[[]*subscription]
+	dsproofs      *Atomic[map[string][]chan struct{}]
+
+	config      clientConfig
+	limiter     *limiter
+	invoke      Invoker
+	invokeBatch BatchInvoker
+	notify      NotificationHandler
+
+	ctx      context.Context
+	cancel   context.CancelFunc
 	quit     chan struct{}
+	dropped  chan error
 	shutdown sync.Once
+	err      *Atomic[error]
+
+	negotiated    *Atomic[[2]string]
+	handshakeDone *Atomic[chan struct{}]
+	handshakeErr  *Atomic[error]
 
-	nextID uint64
+	stateLock      sync.Mutex
+	state          StateEvent
+	stateListeners []*stateListener
+
+	generation  atomic.Uint64
+	latency     atomic.Int64
+	failures    atomic.Int32
+	connectedAt atomic.Int64
+
+	nextID             uint64
+	nextSubscriptionID uint64
 
 	log logger.Logger
 }
 
-func NewClient(ctx context.Context, transport Transport) *Client {
+// NewClient initialize a new client for remote server using an already connected transport.
+// The client shuts down when ctx is cancelled.
+func NewClient(ctx context.Context, transport Transport, opts ...ClientOption) *Client {
 	log := logger.GetLogger(ctx)
 
 	c := &Client{
-		handlers:     MakeAtomic(make(map[uint64]chan *container)),
-		pushHandlers: MakeAtomic(make(map[string][]chan *container)),
+		handlers:      MakeAtomic(make(map[uint64]chan *container)),
+		pushHandlers:  MakeAtomic(make(map[string][]chan *container)),
+		subscriptions: MakeAtomic[[]*subscription](nil),
+		dsproofs:      MakeAtomic(make(map[string][]chan struct{})),
+		negotiated:    MakeAtomic([2]string{}),
+		handshakeDone: MakeAtomic[chan struct{}](nil),
+		handshakeErr:  MakeAtomic[error](nil),
+
+		config: newClientConfig(opts),
+
+		quit:    make(chan struct{}),
+		dropped: make(chan error, 1),
+		err:     MakeAtomic[error](nil),
+		log:     log,
+
+		state: StateEvent{State: StateConnected, Time: time.Now(), Addr: transportAddr(transport)},
+	}
 
-		quit: make(chan struct{}),
-		log:  log,
+	c.limiter = newLimiter(&c.config)
+	c.invoke = chainInterceptors(c.config.interceptors, c.call)
+	c.invokeBatch = chainBatchInterceptors(c.config.batchInterceptors, c.callBatch)
+	if len(c.config.notificationInterceptors) > 0 {
+		c.notify = chainNotificationInterceptors(c.config.notificationInterceptors, c.deliverNotification)
 	}
 
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	go func() {
+		<-c.ctx.Done()
+		c.close(context.Cause(c.ctx))
+	}()
+
 	c.transport = MakeAtomic[Transport](transport)
+	c.connectedAt.Store(time.Now().UnixNano())
 	go c.listen()
 
+	if c.config.handshake {
+		go c.handshake(c.startHandshake())
+	}
+
+	if c.config.keepAliveInterval > 0 {
+		go c.keepAlive()
+	}
+
 	return c
 }
 
 // NewClientTCP initialize a new client for remote server and connects to the remote server using TCP
-func NewClientTCP(ctx context.Context, addr string) (*Client, error) {
-	transport, err := NewTCPTransport(ctx, addr)
+func NewClientTCP(ctx context.Context, addr string, opts ...ClientOption) (*Client, error) {
+	transport, err := NewTCPTransport(ctx, addr, newClientConfig(opts).transportOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewClient(ctx, transport), nil
+	return NewClient(ctx, transport, opts...), nil
 }
 
 // NewClientSSL initialize a new client for remote server and connects to the remote server using SSL
-func NewClientSSL(ctx context.Context, addr string, config *tls.Config) (*Client, error) {
-	transport, err := NewSSLTransport(ctx, addr, config)
+func NewClientSSL(ctx context.Context, addr string, config *tls.Config, opts ...ClientOption) (*Client, error) {
+	transport, err := NewSSLTransport(ctx, addr, config, newClientConfig(opts).transportOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewClient(ctx, transport), nil
+	return NewClient(ctx, transport, opts...), nil
 }
 
 type apiErr struct {
@@ -143,9 +220,10 @@ func (A APIError) Error() string {
 }
 
 type response struct {
-	ID     uint64    `json:"id"`
-	Method string    `json:"method"`
-	Error  *APIError `json:"error,omitempty"`
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Error  *APIError       `json:"error,omitempty"`
 }
 
 func (s *Client) listen() {
@@ -191,56 +269,138 @@ func (s *Client) listen() {
 		select {
 		case <-s.quit:
 			return
-		case <-errors():
-			s.Shutdown()
-		case bytes := <-responses():
-			result := &container{
-				content: bytes,
+		case err := <-errors():
+			if !s.failRequest(err) {
+				s.connectionFailed(err)
 			}
+		case err := <-s.dropped:
+			s.connectionFailed(err)
+		case bytes := <-responses():
+			s.dispatch(bytes)
+		}
+	}
+}
 
-			msg := &response{}
-			err := json.Unmarshal(bytes, msg)
-			if err != nil {
-				s.log.Errorf("Unmarshal received message failed: %v", err)
-				result.err = fmt.Errorf("unmarshal received message failed: %v", err)
-			} else if msg.Error != nil {
-				result.err = msg.Error
-			}
+// dispatch routes a received message to the waiting request or the notification
+// handlers. The responses of a batch request arrive as one JSON array.
+func (s *Client) dispatch(data []byte) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		s.dispatchMessage(data)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(trimmed, &batch); err != nil {
+		s.log.Errorf("Unmarshal received batch failed: %v", err)
+		return
+	}
+
+	for _, msg := range batch {
+		s.dispatchMessage(msg)
+	}
+}
+
+func (s *Client) dispatchMessage(data []byte) {
+	result := &container{
+		content: data,
+	}
+
+	msg := &response{}
+	err := json.Unmarshal(data, msg)
+	if err != nil {
+		s.log.Errorf("Unmarshal received message failed: %v", err)
+		result.err = fmt.Errorf("unmarshal received message failed: %v", err)
+	} else if msg.Error != nil {
+		result.err = msg.Error
+	}
+
+	if len(msg.Method) > 0 {
+		s.config.observer.NotificationReceived(msg.Method)
+
+		if s.notify != nil && result.err == nil {
+			s.notify(msg.Method, msg.Params)
+		} else {
+			s.deliver(msg.Method, result)
+		}
+	} else {
+		c, ok := Get(s.handlers, func(val map[uint64]chan *container) (chan *container, bool) {
+			c, ok := val[msg.ID]
 
-			if len(msg.Method) > 0 {
-				handlers, ok := Get(s.pushHandlers, func(val map[string][]chan *container) ([]chan *container, bool) {
-					handlers, ok := val[msg.Method]
-					return handlers, ok
-				})
-
-				if ok {
-					for _, handler := range handlers {
-						handler <- result
-					}
-				} else {
-					s.log.Warnf("Unknown notification: %s -> %s", msg.Method, result.content)
-				}
-			} else {
-				c, ok := Get(s.handlers, func(val map[uint64]chan *container) (chan *container, bool) {
-					c, ok := val[msg.ID]
-
-					if c == nil {
-						return nil, false
-					}
-
-					return c, ok
-				})
-
-				if ok {
-					c <- result
-				} else {
-					s.log.Warnf("Unexpected container: %v -> %s", msg.ID, result.content)
-				}
+			if c == nil {
+				return nil, false
 			}
+
+			return c, ok
+		})
+
+		if ok {
+			c <- result
+		} else {
+			s.log.Warnf("Unexpected container: %v -> %s", msg.ID, result.content)
 		}
 	}
 }
 
+// deliver passes a notification to the handlers listening for method or, if there
+// are none, to the notification handler of the client.
+func (s *Client) deliver(method string, result *container) {
+	handlers, ok := Get(s.pushHandlers, func(val map[string][]chan *container) ([]chan *container, bool) {
+		handlers, ok := val[method]
+		return handlers, ok
+	})
+
+	if ok {
+		for _, handler := range handlers {
+			handler <- result
+		}
+
+		return
+	}
+
+	if s.config.notificationHandler != nil && result.err == nil {
+		var msg response
+		if err := json.Unmarshal(result.content, &msg); err == nil {
+			s.config.notificationHandler(method, msg.Params)
+			return
+		}
+	}
+
+	s.log.Warnf("Unknown notification: %s -> %s", method, result.content)
+}
+
+// deliverNotification is the innermost NotificationHandler, it encodes the
+// notification again as it may have been changed by an interceptor.
+func (s *Client) deliverNotification(method string, params json.RawMessage) {
+	content, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+	if err != nil {
+		s.log.Errorf("Marshal notification failed: %v", err)
+		return
+	}
+
+	s.deliver(method, &container{content: content})
+}
+
+// connectionFailed reconnects or, without reconnecting mode, shuts the client down.
+func (s *Client) connectionFailed(err error) {
+	if s.config.dial == nil {
+		s.close(err)
+		return
+	}
+
+	s.setState(StateReconnecting, "", err)
+	s.failPending(fmt.Errorf("%w: %w", ErrConnectionLost, err))
+	s.reconnect(err)
+}
+
+// drop makes the listener treat the current connection as failed with err.
+func (s *Client) drop(err error) {
+	select {
+	case s.dropped <- err:
+	default:
+	}
+}
+
 func (s *Client) listenPush(method string) <-chan *container {
 	c := make(chan *container, 1)
 	s.pushHandlers.Change(func(val map[string][]chan *container) (map[string][]chan *container, error) {
@@ -254,19 +414,87 @@ func (s *Client) listenPush(method string) <-chan *container {
 	return c
 }
 
+func (s *Client) unlistenPush(method string, c <-chan *container) {
+	s.pushHandlers.Change(func(val map[string][]chan *container) (map[string][]chan *container, error) {
+		if val == nil {
+			return val, nil
+		}
+
+		val[method] = slices.DeleteFunc(val[method], func(h chan *container) bool {
+			return h == c
+		})
+
+		if len(val[method]) == 0 {
+			delete(val, method)
+		}
+
+		return val, nil
+	})
+}
+
 type request struct {
 	ID     uint64 `json:"id"`
 	Method string `json:"method"`
 	Params []any  `json:"params"`
 }
 
+// request calls method through the interceptors and decodes the response into v.
 func (s *Client) request(ctx context.Context, method string, params []any, v any) error {
+	if v == nil {
+		return s.Call(ctx, method, params, nil)
+	}
+
+	var result json.RawMessage
+	if err := s.Call(ctx, method, params, &result); err != nil {
+		return err
+	}
+
+	// the response types wrap the result
+	return json.Unmarshal(fmt.Appendf(nil, `{"result":%s}`, result), v)
+}
+
+// call is the innermost Invoker, it reports the request to the observer.
+func (s *Client) call(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	start := time.Now()
+	s.config.observer.RequestStarted(method)
+
+	result, err := s.roundTrip(ctx, method, params)
+	s.config.observer.RequestFinished(method, time.Since(start), err)
+
+	return result, err
+}
+
+// roundTrip sends a request and waits for the response. A request timing out
+// counts as a failed health check of the connection.
+func (s *Client) roundTrip(ctx context.Context, method string, params []any) (json.RawMessage, error) {
 	select {
 	case <-s.quit:
-		return ErrServerShutdown
+		return nil, s.Err()
 	default:
 	}
 
+	if method != "server.version" {
+		if err := s.waitHandshake(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	release, err := s.limiter.acquire(ctx, s.quit, s.config.methodCost(method))
+	if err != nil {
+		if s.IsShutdown() {
+			return nil, s.Err()
+		}
+
+		return nil, err
+	}
+
+	defer release()
+
+	ctx, cancel := withTimeout(ctx, method, s.config.methodTimeout(method))
+	defer cancel()
+
+	generation := s.generation.Load()
+
 	msg := request{
 		ID:     atomic.AddUint64(&s.nextID, 1),
 		Method: method,
@@ -275,14 +503,63 @@ func (s *Client) request(ctx context.Context, method string, params []any, v any
 
 	bytes, err := json.Marshal(msg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	bytes = append(bytes, nl)
 
+	c, err := s.register(msg.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.unregister(msg.ID)
+
+	err = s.send(ctx, bytes)
+	if err != nil {
+		if errors.Is(err, ErrTimeout) {
+			s.failed(generation, err)
+		}
+
+		return nil, err
+	}
+
+	var resp *container
+	select {
+	case resp = <-c:
+		s.failures.Store(0)
+	case <-s.quit:
+		return nil, s.Err()
+	case <-ctx.Done():
+		err := contextErr(ctx)
+		if errors.Is(err, ErrTimeout) {
+			s.failed(generation, err)
+		}
+
+		return nil, err
+	}
+
+	resp = s.limitResponse(method, resp)
+	if err := s.checkThrottled(method, resp.err); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result json.RawMessage `json:"result"`
+	}
+
+	if err := json.Unmarshal(resp.content, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Result, nil
+}
+
+// register adds a handler waiting for the response with id.
+func (s *Client) register(id uint64) (chan *container, error) {
 	c := make(chan *container, 1)
 
-	err = s.handlers.Change(func(val map[uint64]chan *container) (map[uint64]chan *container, error) {
+	err := s.handlers.Change(func(val map[uint64]chan *container) (map[uint64]chan *container, error) {
 		if s.IsShutdown() {
 			return val, ErrServerShutdown
 		}
@@ -291,58 +568,80 @@ func (s *Client) request(ctx context.Context, method string, params []any, v any
 			val = make(map[uint64]chan *container)
 		}
 
-		val[msg.ID] = c
+		val[id] = c
 		return val, nil
 	})
 
 	if err != nil {
-		return err
+		if errors.Is(err, ErrServerShutdown) {
+			return nil, s.Err()
+		}
+
+		return nil, err
 	}
 
-	err = s.transport.Do(func(val Transport) error {
-		return val.SendMessage(bytes)
+	return c, nil
+}
+
+func (s *Client) unregister(ids ...uint64) {
+	s.handlers.Change(func(val map[uint64]chan *container) (map[uint64]chan *container, error) {
+		for _, id := range ids {
+			delete(val, id)
+		}
+
+		return val, nil
 	})
+}
 
-	if err != nil {
-		s.handlers.Change(func(val map[uint64]chan *container) (map[uint64]chan *container, error) {
-			delete(val, msg.ID)
-			return val, nil
-		})
-		s.Shutdown()
-		return err
-	}
+// send writes a message to the transport. Without reconnecting mode, a failed write
+// shuts the client down.
+func (s *Client) send(ctx context.Context, bytes []byte) error {
+	err := s.transport.Do(func(val Transport) error {
+		if sender, ok := val.(ContextSender); ok {
+			return sender.SendMessageContext(ctx, bytes)
+		}
 
-	defer func() {
-		s.handlers.Change(func(val map[uint64]chan *container) (map[uint64]chan *container, error) {
-			delete(val, msg.ID)
-			return val, nil
-		})
-	}()
+		return val.SendMessage(bytes)
+	})
 
-	var resp *container
-	select {
-	case resp = <-c:
-	case <-ctx.Done():
-		return ctx.Err()
-	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return contextErr(ctx)
+		}
 
-	if resp.err != nil {
-		return resp.err
-	}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			err = fmt.Errorf("%w: write: %w", ErrTimeout, err)
+		}
 
-	if v != nil {
-		err = json.Unmarshal(resp.content, v)
-		if err != nil {
-			return err
+		if s.config.dial == nil {
+			s.close(err)
 		}
+
+		return err
 	}
 
 	return nil
 }
 
+// Shutdown closes the connection and fails all pending requests with ErrServerShutdown.
 func (s *Client) Shutdown() {
+	s.close(ErrServerShutdown)
+}
+
+// close shuts the client down and records cause as the reason.
+func (s *Client) close(cause error) {
 	s.shutdown.Do(func() {
+		err := ErrServerShutdown
+		if cause != nil && !errors.Is(cause, ErrServerShutdown) {
+			err = fmt.Errorf("%w: %w", ErrServerShutdown, cause)
+		}
+
+		s.err.Change(func(error) (error, error) {
+			return err, nil
+		})
+
 		close(s.quit)
+		s.cancel()
 
 		s.transport.Do(func(val Transport) error {
 			if val != nil {
@@ -353,8 +652,12 @@ func (s *Client) Shutdown() {
 		})
 
 		s.transport.Reset()
+		s.failPending(err)
 		s.handlers.Reset()
 		s.pushHandlers.Reset()
+		s.subscriptions.Reset()
+
+		s.setState(StateShutdown, "", err)
 	})
 }
 
@@ -366,3 +669,36 @@ func (s *Client) IsShutdown() bool {
 	}
 	return false
 }
+
+// Done returns a channel that is closed when the client has shut down.
+func (s *Client) Done() <-chan struct{} {
+	return s.quit
+}
+
+// Err returns nil while the client is running. After shutdown it returns an error
+// matching ErrServerShutdown that wraps the cause, e.g. the transport error or the
+// error of the cancelled context.
+func (s *Client) Err() error {
+	err, _ := Get(s.err, func(val error) (error, bool) {
+		return val, val != nil
+	})
+
+	return err
+}
+
+// failPending fails all requests waiting for a response with err.
+func (s *Client) failPending(err error) {
+	var pending map[uint64]chan *container
+
+	s.handlers.Change(func(val map[uint64]chan *container) (map[uint64]chan *container, error) {
+		pending = val
+		return make(map[uint64]chan *container), nil
+	})
+
+	for _, c := range pending {
+		select {
+		case c <- &container{err: err}:
+		default:
+		}
+	}
+}
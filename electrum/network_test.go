@@ -0,0 +1,109 @@
+package electrum_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestClient_PendingRequestsFailOnDisconnect(t *testing.T) {
+	ctx := context.Background()
+	client, transport := newTestClient(ctx, t)
+	defer client.Shutdown()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Ping(ctx)
+	}()
+
+	<-transport.sent()
+	transport.fail(io.ErrUnexpectedEOF)
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, electrum.ErrServerShutdown)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending request was not failed")
+	}
+
+	<-client.Done()
+	assert.ErrorIs(t, client.Err(), io.ErrUnexpectedEOF)
+}
+
+func TestClient_PendingRequestsFailOnReconnect(t *testing.T) {
+	ctx := context.Background()
+
+	first := NewMockTransport()
+	second := NewMockTransport()
+
+	client := electrum.NewClient(ctx, first, electrum.WithoutHandshake(), electrum.WithReconnect(dialSequence(second)), electrum.WithBackoff(testBackoff))
+	defer client.Shutdown()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Ping(ctx)
+	}()
+
+	<-first.sent()
+	first.fail(io.EOF)
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, electrum.ErrConnectionLost)
+		assert.ErrorIs(t, err, io.EOF)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending request was not failed")
+	}
+
+	assert.NoError(t, client.Err())
+}
+
+func TestClient_Err(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(ctx, t)
+
+	assert.NoError(t, client.Err())
+
+	select {
+	case <-client.Done():
+		t.Fatal("client is done before shutdown")
+	default:
+	}
+
+	client.Shutdown()
+
+	<-client.Done()
+	assert.Equal(t, electrum.ErrServerShutdown, client.Err())
+
+	err := client.Ping(ctx)
+	assert.ErrorIs(t, err, electrum.ErrServerShutdown)
+}
+
+func TestClient_ContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client, transport := newTestClient(ctx, t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Ping(context.Background())
+	}()
+
+	<-transport.sent()
+	cancel()
+
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not shut down")
+	}
+
+	require.True(t, errors.Is(client.Err(), context.Canceled))
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
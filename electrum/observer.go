@@ -0,0 +1,119 @@
+package electrum
+
+import (
+	"time"
+)
+
+// TransportObserver receives the connection events of a transport. The methods are
+// called synchronously from the goroutines of the transport and must not block.
+type TransportObserver interface {
+	// Connected is called when the transport has opened the connection to addr.
+	Connected(addr string)
+	// Disconnected is called once when the connection is closed. err is nil if
+	// it was closed by Close.
+	Disconnected(err error)
+	// MessageSent is called after a message of size bytes has been written.
+	MessageSent(size int)
+	// MessageReceived is called for every message of size bytes read from the connection.
+	MessageReceived(size int)
+}
+
+// Observer receives the request and notification events of a client in addition
+// to the events of its transport, e.g. to export metrics or to trace calls. The
+// methods must not block.
+type Observer interface {
+	TransportObserver
+
+	// RequestStarted is called before a request for method is sent.
+	RequestStarted(method string)
+	// RequestFinished is called when the request for method has been answered or
+	// has failed with err.
+	RequestFinished(method string, duration time.Duration, err error)
+	// NotificationReceived is called for every notification pushed by the server.
+	NotificationReceived(method string)
+}
+
+// NopObserver ignores all events. Embed it to implement only some methods of Observer.
+type NopObserver struct{}
+
+func (NopObserver) Connected(string)                             {}
+func (NopObserver) Disconnected(error)                           {}
+func (NopObserver) MessageSent(int)                              {}
+func (NopObserver) MessageReceived(int)                          {}
+func (NopObserver) RequestStarted(string)                        {}
+func (NopObserver) RequestFinished(string, time.Duration, error) {}
+func (NopObserver) NotificationReceived(string)                  {}
+
+// WithObserver reports the events of the client to observer. The transport created
+// by the NewClientXXX constructors reports to observer as well, transports returned
+// by a DialFunc need WithTransportObserver.
+func WithObserver(observer Observer) ClientOption {
+	return func(c *clientConfig) {
+		if observer == nil {
+			return
+		}
+
+		c.observer = observer
+		c.transportOpts = append(c.transportOpts, WithTransportObserver(observer))
+	}
+}
+
+// WithTransportObserver reports the connection events of the transport to observer.
+func WithTransportObserver(observer TransportObserver) TransportOption {
+	return func(c *transportConfig) {
+		if observer != nil {
+			c.observer = observer
+		}
+	}
+}
+
+// Stats is a snapshot of the state of a client.
+type Stats struct {
+	// Pending is the number of requests waiting for a response.
+	Pending int
+	// Subscriptions is the number of active server subscriptions.
+	Subscriptions int
+	// Listeners is the number of channels waiting for notifications.
+	Listeners int
+	// Reconnects counts how often the connection has been replaced.
+	Reconnects uint64
+	// ConnectedAt is the time the current connection has been established.
+	ConnectedAt time.Time
+	// ConnectionAge is the time since ConnectedAt.
+	ConnectionAge time.Duration
+	// Latency is the round-trip time of the last keepalive ping.
+	Latency time.Duration
+}
+
+// Stats returns a snapshot of the pending requests, subscriptions and the age of
+// the current connection.
+func (s *Client) Stats() Stats {
+	pending, _ := Get(s.handlers, func(val map[uint64]chan *container) (int, bool) {
+		return len(val), true
+	})
+
+	subscriptions, _ := Get(s.subscriptions, func(val []*subscription) (int, bool) {
+		return len(val), true
+	})
+
+	listeners, _ := Get(s.pushHandlers, func(val map[string][]chan *container) (int, bool) {
+		n := 0
+		for _, handlers := range val {
+			n += len(handlers)
+		}
+
+		return n, true
+	})
+
+	connectedAt := time.Unix(0, s.connectedAt.Load())
+
+	return Stats{
+		Pending:       pending,
+		Subscriptions: subscriptions,
+		Listeners:     listeners,
+		Reconnects:    s.generation.Load(),
+		ConnectedAt:   connectedAt,
+		ConnectionAge: time.Since(connectedAt),
+		Latency:       s.Latency(),
+	}
+}
@@ -0,0 +1,174 @@
+package electrum_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+	"github.com/zauberhaus/go-electrum/electrum/electrumtest"
+)
+
+type recordingObserver struct {
+	electrum.NopObserver
+
+	lock     sync.Mutex
+	events   []string
+	sent     int
+	received int
+}
+
+func (o *recordingObserver) record(format string, args ...any) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.events = append(o.events, fmt.Sprintf(format, args...))
+}
+
+func (o *recordingObserver) Connected(string) {
+	o.record("connected")
+}
+
+func (o *recordingObserver) Disconnected(err error) {
+	o.record("disconnected %v", err)
+}
+
+func (o *recordingObserver) MessageSent(size int) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.sent += size
+}
+
+func (o *recordingObserver) MessageReceived(size int) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.received += size
+}
+
+func (o *recordingObserver) RequestStarted(method string) {
+	o.record("start %s", method)
+}
+
+func (o *recordingObserver) RequestFinished(method string, duration time.Duration, err error) {
+	o.record("finish %s %v", method, err)
+}
+
+func (o *recordingObserver) NotificationReceived(method string) {
+	o.record("notification %s", method)
+}
+
+func (o *recordingObserver) Events() []string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return append([]string(nil), o.events...)
+}
+
+func TestObserver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	addr, err := server.Listen()
+	require.NoError(t, err)
+
+	observer := &recordingObserver{}
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake(), electrum.WithObserver(observer))
+	require.NoError(t, err)
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	<-headers
+
+	batch := client.NewBatch()
+	batch.GetBalance("0000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, batch.Send(ctx))
+
+	server.Mine(1)
+
+	select {
+	case <-headers:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no notification")
+	}
+
+	client.Shutdown()
+
+	assert.Equal(t, []string{
+		"connected",
+		"start blockchain.headers.subscribe",
+		"finish blockchain.headers.subscribe <nil>",
+		"start blockchain.scripthash.get_balance",
+		"finish blockchain.scripthash.get_balance <nil>",
+		"notification blockchain.headers.subscribe",
+		"disconnected <nil>",
+	}, observer.Events())
+
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	assert.Positive(t, observer.sent)
+	assert.Positive(t, observer.received)
+}
+
+func TestObserver_Disconnected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	observer := &recordingObserver{}
+
+	client := electrum.NewClient(ctx, server.Transport(ctx, electrum.WithTransportObserver(observer)))
+	defer client.Shutdown()
+
+	_, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+
+	server.Disconnect()
+	<-client.Done()
+
+	events := observer.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "connected", events[0])
+	assert.NotEqual(t, "disconnected <nil>", events[1])
+}
+
+func TestClient_Stats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client := server.Client(ctx)
+	defer client.Shutdown()
+
+	stats := client.Stats()
+	assert.Equal(t, 0, stats.Pending)
+	assert.Equal(t, 0, stats.Subscriptions)
+	assert.Equal(t, 0, stats.Listeners)
+	assert.Equal(t, uint64(0), stats.Reconnects)
+	assert.WithinDuration(t, time.Now(), stats.ConnectedAt, time.Minute)
+
+	_, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+
+	client.SubscribeScripthash()
+
+	stats = client.Stats()
+	assert.Equal(t, 0, stats.Pending)
+	assert.Equal(t, 2, stats.Subscriptions)
+	assert.Equal(t, 2, stats.Listeners)
+	assert.Positive(t, stats.ConnectionAge)
+}
@@ -0,0 +1,179 @@
+package electrum
+
+import (
+	"crypto/tls"
+	"maps"
+	"net"
+	"time"
+)
+
+// clientConfig stores the optional settings of a Client.
+type clientConfig struct {
+	dial    DialFunc
+	backoff Backoff
+
+	keepAliveInterval time.Duration
+	keepAliveFailures int
+
+	responseLimits map[string]int
+
+	timeout        time.Duration
+	methodTimeouts map[string]time.Duration
+
+	maxInFlight int
+	rate        float64
+	burst       int
+	methodCosts map[string]float64
+
+	interceptors             []Interceptor
+	batchInterceptors        []BatchInterceptor
+	notificationInterceptors []NotificationInterceptor
+	notificationHandler      NotificationHandler
+
+	observer Observer
+
+	protocolMin string
+	protocolMax string
+	handshake   bool
+
+	network *Network
+
+	transportOpts []TransportOption
+}
+
+// ClientOption configures optional behaviour of a Client.
+type ClientOption func(*clientConfig)
+
+func newClientConfig(opts []ClientOption) clientConfig {
+	cfg := clientConfig{
+		backoff:           DefaultBackoff,
+		timeout:           DefaultTimeout,
+		methodTimeouts:    maps.Clone(DefaultMethodTimeouts),
+		methodCosts:       maps.Clone(DefaultMethodCosts),
+		keepAliveFailures: DefaultKeepAliveFailures,
+		observer:          NopObserver{},
+		protocolMin:       ProtocolVersion,
+		protocolMax:       ProtocolMax,
+		handshake:         true,
+		network:           BitcoinMainNet,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithReconnect enables the reconnecting mode. When the transport fails the client
+// redials through dial, repeats the server.version handshake and restores all
+// active subscriptions instead of shutting down.
+func WithReconnect(dial DialFunc) ClientOption {
+	return func(c *clientConfig) {
+		c.dial = dial
+	}
+}
+
+// WithBackoff sets the delay policy between reconnection attempts and of the
+// pauses after throttling errors of the server.
+func WithBackoff(backoff Backoff) ClientOption {
+	return func(c *clientConfig) {
+		c.backoff = backoff
+	}
+}
+
+// WithKeepAlive makes the client ping the server every interval. If failures pings
+// or timed out requests in a row fail, the connection is considered dead and is
+// reconnected or, without reconnecting mode, shut down with ErrUnresponsive.
+// Without keepalive only timed out requests count, up to DefaultKeepAliveFailures.
+func WithKeepAlive(interval time.Duration, failures int) ClientOption {
+	return func(c *clientConfig) {
+		c.keepAliveInterval = interval
+		c.keepAliveFailures = max(failures, 1)
+	}
+}
+
+// WithTransportOptions passes opts to the transport created by the NewClientXXX constructors.
+func WithTransportOptions(opts ...TransportOption) ClientOption {
+	return func(c *clientConfig) {
+		c.transportOpts = append(c.transportOpts, opts...)
+	}
+}
+
+// transportConfig stores the optional settings of a transport.
+type transportConfig struct {
+	dialer         Dialer
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	writeQueueSize int
+	maxMessageSize int
+
+	trustStore TrustStore
+	pinType    PinType
+
+	observer TransportObserver
+}
+
+// TransportOption configures optional behaviour of a transport.
+type TransportOption func(*transportConfig)
+
+func newTransportConfig(opts []TransportOption) transportConfig {
+	cfg := transportConfig{
+		dialer:       &net.Dialer{},
+		writeTimeout: DefaultWriteTimeout,
+		observer:     NopObserver{},
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.maxMessageSize <= 0 {
+		cfg.maxMessageSize = DefaultMaxMessageSize
+	}
+
+	return cfg
+}
+
+// tlsConfig applies the certificate pinning settings to config for addr.
+func (c *transportConfig) tlsConfig(addr string, config *tls.Config) *tls.Config {
+	if c.trustStore == nil {
+		return config
+	}
+
+	return TOFUConfig(config, c.trustStore, addr, c.pinType)
+}
+
+// WithDialer sets the dialer used to open the network connection, e.g. a SOCKS5Dialer.
+func WithDialer(dialer Dialer) TransportOption {
+	return func(c *transportConfig) {
+		if dialer != nil {
+			c.dialer = dialer
+		}
+	}
+}
+
+// WithReadTimeout closes the connection with a timeout error if nothing has been
+// received for d. Combine it with WithKeepAlive using a shorter interval, otherwise
+// idle connections are closed as well.
+func WithReadTimeout(d time.Duration) TransportOption {
+	return func(c *transportConfig) {
+		c.readTimeout = d
+	}
+}
+
+// WithWriteTimeout limits the time a write may take if the request has no deadline.
+// A connection whose write times out is dropped. Zero disables the timeout.
+func WithWriteTimeout(d time.Duration) TransportOption {
+	return func(c *transportConfig) {
+		c.writeTimeout = d
+	}
+}
+
+// WithWriteQueue sets the number of outbound messages a transport queues before
+// SendMessage blocks.
+func WithWriteQueue(size int) TransportOption {
+	return func(c *transportConfig) {
+		c.writeQueueSize = size
+	}
+}
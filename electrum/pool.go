@@ -0,0 +1,511 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zauberhaus/logger"
+)
+
+const (
+	// DefaultMaxLag is the number of blocks a pool member may fall behind the best known height.
+	DefaultMaxLag = 2
+
+	// DefaultMaxFailures is the number of failed calls in a row after which a pool member
+	// is taken out of rotation.
+	DefaultMaxFailures = 3
+
+	// DefaultCooldown is the time a failing pool member stays out of rotation.
+	DefaultCooldown = 30 * time.Second
+)
+
+var (
+	// ErrNoServer throws an error if a pool or quorum has no members.
+	ErrNoServer = errors.New("no server configured")
+
+	// ErrNoHealthyServer throws an error if no pool member is available for a call.
+	ErrNoHealthyServer = errors.New("no healthy server available")
+)
+
+// Member is a named server connection of a Pool or a Quorum.
+type Member struct {
+	Name   string
+	Client *Client
+}
+
+// ServerStatus reports the health of a pool member.
+type ServerStatus struct {
+	Name      string
+	Healthy   bool          // available for calls
+	Lagging   bool          // ejected because it is behind the best known height
+	Latency   time.Duration // moving average of the call round-trip time
+	Height    int32         // last reported tip height
+	Failures  int           // failed calls in a row
+	LastError error
+}
+
+type poolConfig struct {
+	maxLag      int32
+	maxFailures int
+	cooldown    time.Duration
+}
+
+// PoolOption configures optional behaviour of a Pool.
+type PoolOption func(*poolConfig)
+
+// WithMaxLag ejects members which are more than blocks behind the best known
+// height until they caught up.
+func WithMaxLag(blocks int32) PoolOption {
+	return func(c *poolConfig) {
+		c.maxLag = blocks
+	}
+}
+
+// WithFailureThreshold takes a member out of rotation for cooldown after failures
+// failed calls in a row.
+func WithFailureThreshold(failures int, cooldown time.Duration) PoolOption {
+	return func(c *poolConfig) {
+		c.maxFailures = max(failures, 1)
+		c.cooldown = cooldown
+	}
+}
+
+type poolMember struct {
+	Member
+
+	latency atomic.Int64
+	height  atomic.Int32
+
+	failures int
+	until    time.Time
+	lastErr  error
+	lock     sync.Mutex
+}
+
+func (m *poolMember) success(d time.Duration) {
+	// exponential moving average
+	if old := m.latency.Load(); old > 0 {
+		d = (4*time.Duration(old) + d) / 5
+	}
+
+	m.latency.Store(int64(d))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.failures = 0
+}
+
+func (m *poolMember) failure(err error, cfg poolConfig) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.failures++
+	m.lastErr = err
+
+	if m.failures >= cfg.maxFailures {
+		m.until = time.Now().Add(cfg.cooldown)
+	}
+}
+
+// Pool spreads calls over several servers. Every call goes to the healthy member
+// with the lowest latency, idempotent calls are retried on the next member if a
+// server fails. Members falling behind the best known tip height are ejected
+// until they caught up. Subscriptions are not supported by a Pool, subscribe
+// through a member Client instead.
+type Pool struct {
+	members []*poolMember
+	config  poolConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	log logger.Logger
+}
+
+// NewPool initialize a new pool of already connected clients. The pool tracks the
+// tip height of every member through a headers subscription.
+func NewPool(ctx context.Context, members []Member, opts ...PoolOption) (*Pool, error) {
+	if len(members) == 0 {
+		return nil, ErrNoServer
+	}
+
+	cfg := poolConfig{
+		maxLag:      DefaultMaxLag,
+		maxFailures: DefaultMaxFailures,
+		cooldown:    DefaultCooldown,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	p := &Pool{
+		config: cfg,
+		log:    logger.GetLogger(ctx),
+	}
+
+	p.ctx, p.cancel = context.WithCancel(ctx)
+
+	for _, member := range members {
+		m := &poolMember{Member: member}
+		p.members = append(p.members, m)
+
+		go p.track(m)
+	}
+
+	return p, nil
+}
+
+// track follows the tip height of m.
+func (p *Pool) track(m *poolMember) {
+	for attempt := 0; ; attempt++ {
+		headers, err := m.Client.SubscribeHeaders(p.ctx)
+		if err == nil {
+			p.follow(m, headers)
+			return
+		}
+
+		p.log.Warnf("Subscribing headers of %s failed: %v", m.Name, err)
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-m.Client.Done():
+			return
+		case <-time.After(DefaultBackoff.Duration(attempt)):
+		}
+	}
+}
+
+func (p *Pool) follow(m *poolMember, headers <-chan *SubscribeHeadersResult) {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-m.Client.Done():
+			return
+		case header := <-headers:
+			if header != nil {
+				m.height.Store(header.Height)
+			}
+		}
+	}
+}
+
+// Height returns the best tip height reported by any member.
+func (p *Pool) Height() int32 {
+	var best int32
+	for _, m := range p.members {
+		best = max(best, m.height.Load())
+	}
+
+	return best
+}
+
+// Status returns the health of all members.
+func (p *Pool) Status() []ServerStatus {
+	best := p.Height()
+	now := time.Now()
+
+	status := make([]ServerStatus, len(p.members))
+	for i, m := range p.members {
+		m.lock.Lock()
+		status[i] = ServerStatus{
+			Name:      m.Name,
+			Latency:   time.Duration(m.latency.Load()),
+			Height:    m.height.Load(),
+			Failures:  m.failures,
+			LastError: m.lastErr,
+		}
+		coolingDown := now.Before(m.until)
+		m.lock.Unlock()
+
+		status[i].Lagging = best-status[i].Height > p.config.maxLag
+		status[i].Healthy = !m.Client.IsShutdown() && !coolingDown && !status[i].Lagging
+	}
+
+	return status
+}
+
+// Members returns the members of the pool.
+func (p *Pool) Members() []Member {
+	members := make([]Member, len(p.members))
+	for i, m := range p.members {
+		members[i] = m.Member
+	}
+
+	return members
+}
+
+// pick returns the healthy member with the lowest latency which has not been tried yet.
+func (p *Pool) pick(tried []*poolMember) *poolMember {
+	status := p.Status()
+
+	var candidates []*poolMember
+	for i, m := range p.members {
+		if status[i].Healthy && !slices.Contains(tried, m) {
+			candidates = append(candidates, m)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// members without a measurement come first to get one
+	slices.SortStableFunc(candidates, func(a, b *poolMember) int {
+		return int(a.latency.Load() - b.latency.Load())
+	})
+
+	return candidates[0]
+}
+
+// Shutdown stops tracking the members and shuts all of them down.
+func (p *Pool) Shutdown() {
+	p.cancel()
+
+	for _, m := range p.members {
+		m.Client.Shutdown()
+	}
+}
+
+// isServerFailure reports whether err is caused by the server or the connection
+// rather than being a valid answer to the request.
+func isServerFailure(err error) bool {
+	var apiErr *APIError
+	return !errors.As(err, &apiErr) && !errors.Is(err, ErrMessageTooLarge) && !errors.Is(err, ErrCheckpointHeight)
+}
+
+// poolCall runs f on the best member. Idempotent calls are retried on the next
+// member if the server fails. Members lacking the method are skipped.
+func poolCall[T any](ctx context.Context, p *Pool, idempotent bool, f func(c *Client) (T, error)) (T, error) {
+	var zero T
+	var tried []*poolMember
+	var errs []error
+
+	for {
+		m := p.pick(tried)
+		if m == nil {
+			if len(errs) == 0 {
+				return zero, ErrNoHealthyServer
+			}
+
+			return zero, fmt.Errorf("%w: %w", ErrNoHealthyServer, errors.Join(errs...))
+		}
+
+		tried = append(tried, m)
+
+		start := time.Now()
+		result, err := f(m.Client)
+
+		// the member lacks the method in its protocol version, nothing was sent
+		if errors.Is(err, ErrNotImplemented) || errors.Is(err, ErrDeprecated) {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Name, err))
+			continue
+		}
+
+		if err == nil || !isServerFailure(err) {
+			m.success(time.Since(start))
+			return result, err
+		}
+
+		if ctx.Err() != nil {
+			return zero, err
+		}
+
+		m.failure(err, p.config)
+		p.log.Warnf("Call to %s failed: %v", m.Name, err)
+
+		if !idempotent {
+			return zero, err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", m.Name, err))
+	}
+}
+
+// GetBalance returns the confirmed and unconfirmed balance for a scripthash.
+func (p *Pool) GetBalance(ctx context.Context, scripthash string) (GetBalanceResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (GetBalanceResult, error) {
+		return c.GetBalance(ctx, scripthash)
+	})
+}
+
+// GetHistory returns the confirmed and unconfirmed history for a scripthash.
+func (p *Pool) GetHistory(ctx context.Context, scripthash string) ([]*GetMempoolResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) ([]*GetMempoolResult, error) {
+		return c.GetHistory(ctx, scripthash)
+	})
+}
+
+// GetMempool returns the unconfirmed transacations of a scripthash.
+func (p *Pool) GetMempool(ctx context.Context, scripthash string) ([]*GetMempoolResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) ([]*GetMempoolResult, error) {
+		return c.GetMempool(ctx, scripthash)
+	})
+}
+
+// ListUnspent returns an ordered list of UTXOs for a scripthash, optionally filtered
+// by their CashTokens.
+func (p *Pool) ListUnspent(ctx context.Context, scripthash string, tokenFilter ...TokenFilter) ([]*ListUnspentResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) ([]*ListUnspentResult, error) {
+		return c.ListUnspent(ctx, scripthash, tokenFilter...)
+	})
+}
+
+// BroadcastTransaction sends a raw transaction to a single member. It is not retried
+// on failure, because the server may have relayed the transaction already.
+func (p *Pool) BroadcastTransaction(ctx context.Context, rawTx string) (string, error) {
+	return poolCall(ctx, p, false, func(c *Client) (string, error) {
+		return c.BroadcastTransaction(ctx, rawTx)
+	})
+}
+
+// GetTransaction gets the detailed information for a transaction.
+func (p *Pool) GetTransaction(ctx context.Context, txHash string) (*GetTransactionResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*GetTransactionResult, error) {
+		return c.GetTransaction(ctx, txHash)
+	})
+}
+
+// GetRawTransaction gets a raw encoded transaction.
+func (p *Pool) GetRawTransaction(ctx context.Context, txHash string) (string, error) {
+	return poolCall(ctx, p, true, func(c *Client) (string, error) {
+		return c.GetRawTransaction(ctx, txHash)
+	})
+}
+
+// GetMerkleProof returns the merkle proof for a confirmed transaction.
+func (p *Pool) GetMerkleProof(ctx context.Context, txHash string, height uint32) (*GetMerkleProofResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*GetMerkleProofResult, error) {
+		return c.GetMerkleProof(ctx, txHash, height)
+	})
+}
+
+// GetHashFromPosition returns the transaction hash for a specific position in a block.
+func (p *Pool) GetHashFromPosition(ctx context.Context, height, position uint32) (string, error) {
+	return poolCall(ctx, p, true, func(c *Client) (string, error) {
+		return c.GetHashFromPosition(ctx, height, position)
+	})
+}
+
+// GetMerkleProofFromPosition returns the merkle proof for a specific position in a block.
+func (p *Pool) GetMerkleProofFromPosition(ctx context.Context, height, position uint32) (*GetMerkleProofFromPosResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*GetMerkleProofFromPosResult, error) {
+		return c.GetMerkleProofFromPosition(ctx, height, position)
+	})
+}
+
+// GetBlockHeader returns the block header at a specific height.
+func (p *Pool) GetBlockHeader(ctx context.Context, height uint32, checkpointHeight ...uint32) (*GetBlockHeaderResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*GetBlockHeaderResult, error) {
+		return c.GetBlockHeader(ctx, height, checkpointHeight...)
+	})
+}
+
+// GetBlockHeaders return a concatenated chunk of block headers.
+func (p *Pool) GetBlockHeaders(ctx context.Context, startHeight, count uint32, checkpointHeight ...uint32) (*GetBlockHeadersResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*GetBlockHeadersResult, error) {
+		return c.GetBlockHeaders(ctx, startHeight, count, checkpointHeight...)
+	})
+}
+
+// GetDSProof returns the double-spend proof of a transaction or nil if there is none.
+func (p *Pool) GetDSProof(ctx context.Context, hash string) (*DSProofResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*DSProofResult, error) {
+		return c.GetDSProof(ctx, hash)
+	})
+}
+
+// ListDSProofs returns the ids of all double-spend proofs known to a member.
+func (p *Pool) ListDSProofs(ctx context.Context) ([]string, error) {
+	return poolCall(ctx, p, true, func(c *Client) ([]string, error) {
+		return c.ListDSProofs(ctx)
+	})
+}
+
+// GetFee returns the estimated transaction fee per kilobytes for a transaction
+// to be confirmed within a target number of blocks.
+func (p *Pool) GetFee(ctx context.Context, target uint32) (float32, error) {
+	return poolCall(ctx, p, true, func(c *Client) (float32, error) {
+		return c.GetFee(ctx, target)
+	})
+}
+
+// GetRelayFee returns the minimum fee a transaction must pay to be accepted into the
+// remote server memory pool.
+func (p *Pool) GetRelayFee(ctx context.Context) (float32, error) {
+	return poolCall(ctx, p, true, func(c *Client) (float32, error) {
+		return c.GetRelayFee(ctx)
+	})
+}
+
+// GetMempoolInfo returns the fee limits of the memory pool of a member. Members
+// which negotiated a protocol older than 1.6 are skipped.
+func (p *Pool) GetMempoolInfo(ctx context.Context) (*GetMempoolInfoResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*GetMempoolInfoResult, error) {
+		return c.GetMempoolInfo(ctx)
+	})
+}
+
+// GetFeeHistogram returns a histogram of the fee rates paid by transactions in the
+// memory pool, weighted by transacation size.
+func (p *Pool) GetFeeHistogram(ctx context.Context) (map[uint32]uint64, error) {
+	return poolCall(ctx, p, true, func(c *Client) (map[uint32]uint64, error) {
+		return c.GetFeeHistogram(ctx)
+	})
+}
+
+// Ping send a ping to the best member.
+func (p *Pool) Ping(ctx context.Context) error {
+	_, err := poolCall(ctx, p, true, func(c *Client) (struct{}, error) {
+		return struct{}{}, c.Ping(ctx)
+	})
+
+	return err
+}
+
+// ServerBanner returns the banner of a member.
+func (p *Pool) ServerBanner(ctx context.Context) (string, error) {
+	return poolCall(ctx, p, true, func(c *Client) (string, error) {
+		return c.ServerBanner(ctx)
+	})
+}
+
+// ServerDonation returns the donation address of a member.
+func (p *Pool) ServerDonation(ctx context.Context) (string, error) {
+	return poolCall(ctx, p, true, func(c *Client) (string, error) {
+		return c.ServerDonation(ctx)
+	})
+}
+
+// ServerFeatures returns a list of features and services supported by a member.
+func (p *Pool) ServerFeatures(ctx context.Context) (*ServerFeaturesResult, error) {
+	return poolCall(ctx, p, true, func(c *Client) (*ServerFeaturesResult, error) {
+		return c.ServerFeatures(ctx)
+	})
+}
+
+// ServerPeers returns a list of peers a member is aware of.
+func (p *Pool) ServerPeers(ctx context.Context) ([]*Peer, error) {
+	return poolCall(ctx, p, true, func(c *Client) ([]*Peer, error) {
+		return c.ServerPeers(ctx)
+	})
+}
+
+// Call sends a request for any method to a member and decodes the result into
+// result. It is not retried on failure, because the pool can not know whether
+// method is idempotent.
+func (p *Pool) Call(ctx context.Context, method string, params []any, result any) error {
+	_, err := poolCall(ctx, p, false, func(c *Client) (struct{}, error) {
+		return struct{}{}, c.Call(ctx, method, params, result)
+	})
+
+	return err
+}
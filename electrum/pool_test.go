@@ -0,0 +1,260 @@
+package electrum_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// newPoolMember connects to a server at height answering server.banner with name.
+func newPoolMember(ctx context.Context, t *testing.T, name string, height int32) electrum.Member {
+	t.Helper()
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		switch method {
+		case "blockchain.headers.subscribe":
+			return map[string]any{"height": height, "hex": "00"}
+		case "server.banner":
+			return name
+		case "blockchain.transaction.broadcast":
+			return name
+		case "blockchain.transaction.dsproof.get":
+			return map[string]any{"dspid": name, "txid": "tx"}
+		case "blockchain.transaction.dsproof.list":
+			return []string{name}
+		case "mempool.get_info":
+			return map[string]float64{"mempoolminfee": 0.00002, "minrelaytxfee": 0.00001, "incrementalrelayfee": 0.00001}
+		}
+
+		return nil
+	})
+
+	client, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+
+	return electrum.Member{Name: name, Client: client}
+}
+
+// newBrokenMember connects to a server which drops the connection when it receives
+// a request for method.
+func newBrokenMember(ctx context.Context, t *testing.T, name string, method string) electrum.Member {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadBytes('\n')
+					if err != nil {
+						return
+					}
+
+					var req struct {
+						ID     uint64 `json:"id"`
+						Method string `json:"method"`
+					}
+					if err := json.Unmarshal(line, &req); err != nil || req.Method == method {
+						return
+					}
+
+					var result any
+					if req.Method == "server.version" {
+						result = []string{"Fake 1.0", electrum.ProtocolMax}
+					}
+
+					resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+					if _, err := conn.Write(append(resp, '\n')); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	client, err := electrum.NewClientTCP(ctx, l.Addr().String())
+	require.NoError(t, err)
+
+	return electrum.Member{Name: name, Client: client}
+}
+
+func TestPool_LagEjection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := electrum.NewPool(ctx, []electrum.Member{
+		newPoolMember(ctx, t, "behind", 90),
+		newPoolMember(ctx, t, "ahead", 100),
+	})
+	require.NoError(t, err)
+	defer pool.Shutdown()
+
+	require.Eventually(t, func() bool {
+		return pool.Height() == 100 && pool.Status()[0].Height == 90
+	}, 2*time.Second, 10*time.Millisecond)
+
+	status := pool.Status()
+	assert.True(t, status[0].Lagging)
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+
+	for i := 0; i < 5; i++ {
+		banner, err := pool.ServerBanner(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "ahead", banner)
+	}
+}
+
+func TestPool_Failover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := electrum.NewPool(ctx, []electrum.Member{
+		newBrokenMember(ctx, t, "broken", "server.banner"),
+		newPoolMember(ctx, t, "good", 0),
+	})
+	require.NoError(t, err)
+	defer pool.Shutdown()
+
+	banner, err := pool.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "good", banner)
+
+	status := pool.Status()
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+	assert.Positive(t, status[1].Latency)
+}
+
+func TestPool_NoRetryForBroadcast(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := electrum.NewPool(ctx, []electrum.Member{
+		newBrokenMember(ctx, t, "broken", "blockchain.transaction.broadcast"),
+		newPoolMember(ctx, t, "good", 0),
+	})
+	require.NoError(t, err)
+	defer pool.Shutdown()
+
+	_, err = pool.BroadcastTransaction(ctx, "00")
+	assert.ErrorIs(t, err, electrum.ErrServerShutdown)
+
+	// the next call goes to the remaining member
+	txid, err := pool.BroadcastTransaction(ctx, "00")
+	require.NoError(t, err)
+	assert.Equal(t, "good", txid)
+}
+
+func TestPool_NoHealthyServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	member := newPoolMember(ctx, t, "single", 0)
+
+	pool, err := electrum.NewPool(ctx, []electrum.Member{member})
+	require.NoError(t, err)
+
+	pool.Shutdown()
+
+	_, err = pool.GetBalance(ctx, "sh")
+	assert.ErrorIs(t, err, electrum.ErrNoHealthyServer)
+
+	_, err = electrum.NewPool(ctx, nil)
+	assert.ErrorIs(t, err, electrum.ErrNoServer)
+}
+
+func TestPool_GetMempoolInfo(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.4")
+	old, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+
+	pool, err := electrum.NewPool(ctx, []electrum.Member{
+		{Name: "old", Client: old},
+		newBrokenMember(ctx, t, "broken", "mempool.get_info"),
+		newPoolMember(ctx, t, "good", 0),
+	})
+	require.NoError(t, err)
+	defer pool.Shutdown()
+
+	require.Eventually(t, func() bool {
+		return old.ProtocolVersion() == "1.4"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	info, err := pool.GetMempoolInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &electrum.GetMempoolInfoResult{MempoolMinFee: 0.00002, MinRelayTxFee: 0.00001, IncrementalRelayFee: 0.00001}, info)
+
+	// the old member was skipped without counting as failure
+	status := pool.Status()
+	assert.True(t, status[0].Healthy)
+	assert.Zero(t, status[0].Failures)
+	assert.NotContains(t, requests(), "mempool.get_info []")
+	assert.False(t, status[1].Healthy)
+}
+
+func TestPool_DSProof(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := electrum.NewPool(ctx, []electrum.Member{
+		newBrokenMember(ctx, t, "broken", "blockchain.transaction.dsproof.get"),
+		newPoolMember(ctx, t, "good", 0),
+	})
+	require.NoError(t, err)
+	defer pool.Shutdown()
+
+	proof, err := pool.GetDSProof(ctx, "tx")
+	require.NoError(t, err)
+	assert.Equal(t, "good", proof.DSPID)
+	assert.Equal(t, "tx", proof.TxID)
+
+	ids, err := pool.ListDSProofs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"good"}, ids)
+
+	status := pool.Status()
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+}
+
+func TestPool_Call(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := electrum.NewPool(ctx, []electrum.Member{
+		newBrokenMember(ctx, t, "broken", "server.banner"),
+		newPoolMember(ctx, t, "good", 0),
+	})
+	require.NoError(t, err)
+	defer pool.Shutdown()
+
+	var banner string
+	err = pool.Call(ctx, "server.banner", nil, &banner)
+	assert.ErrorIs(t, err, electrum.ErrServerShutdown)
+
+	// the next call goes to the remaining member
+	require.NoError(t, pool.Call(ctx, "server.banner", nil, &banner))
+	assert.Equal(t, "good", banner)
+}
@@ -0,0 +1,314 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zauberhaus/logger"
+)
+
+// DefaultQuorumGrace is the time a quorum waits for the late answers after the
+// result of a call has been returned.
+const DefaultQuorumGrace = 5 * time.Second
+
+var (
+	// ErrDisagreement throws an error if not enough servers of a quorum return the same answer.
+	ErrDisagreement = errors.New("servers disagree")
+
+	// ErrInvalidThreshold throws an error if a quorum threshold can not be reached.
+	ErrInvalidThreshold = errors.New("invalid quorum threshold")
+)
+
+// QuorumAnswer is the answer of a single server to a quorum call.
+type QuorumAnswer struct {
+	Server string
+	Result any
+	Err    error
+}
+
+func (a QuorumAnswer) String() string {
+	if a.Err != nil {
+		return fmt.Sprintf("%s: error %v", a.Server, a.Err)
+	}
+
+	data, _ := json.Marshal(a.Result)
+	return fmt.Sprintf("%s: %s", a.Server, data)
+}
+
+// DisagreementError is returned if less than the threshold of servers agree on the
+// result of a call. Answers lists what every server answered.
+type DisagreementError struct {
+	Method    string
+	Threshold int
+	Answers   []QuorumAnswer
+}
+
+func (e *DisagreementError) Error() string {
+	answers := make([]string, len(e.Answers))
+	for i, a := range e.Answers {
+		answers[i] = a.String()
+	}
+
+	return fmt.Sprintf("%s: %s needs %d matching answers, got %s",
+		ErrDisagreement, e.Method, e.Threshold, strings.Join(answers, ", "))
+}
+
+func (e *DisagreementError) Unwrap() error {
+	return ErrDisagreement
+}
+
+// DissentHandler is called with the answers of the members which returned another
+// result than the one a quorum agreed on for method.
+type DissentHandler func(method string, dissent []QuorumAnswer)
+
+type quorumConfig struct {
+	threshold int
+	grace     time.Duration
+	onDissent DissentHandler
+}
+
+// QuorumOption configures optional behaviour of a Quorum.
+type QuorumOption func(*quorumConfig)
+
+// WithThreshold sets the number of servers which must return the same answer.
+// The default is a simple majority.
+func WithThreshold(n int) QuorumOption {
+	return func(c *quorumConfig) {
+		c.threshold = n
+	}
+}
+
+// WithGracePeriod sets how long a quorum waits for the late answers of a call, to
+// find the members disagreeing with the returned result. Zero stops waiting.
+func WithGracePeriod(d time.Duration) QuorumOption {
+	return func(c *quorumConfig) {
+		c.grace = d
+	}
+}
+
+// WithDissentHandler sets a handler for the members disagreeing with the result of
+// a call, including those answering within the grace period after it has been
+// returned. Without a handler the dissent is logged.
+func WithDissentHandler(handler DissentHandler) QuorumOption {
+	return func(c *quorumConfig) {
+		c.onDissent = handler
+	}
+}
+
+// Quorum sends every read to all members and returns a result only if enough of
+// them agree, protecting against a single server lying about the chain or a wallet.
+type Quorum struct {
+	members   []Member
+	threshold int
+	grace     time.Duration
+	onDissent DissentHandler
+}
+
+// NewQuorum initialize a new quorum of already connected clients.
+func NewQuorum(members []Member, opts ...QuorumOption) (*Quorum, error) {
+	if len(members) == 0 {
+		return nil, ErrNoServer
+	}
+
+	cfg := quorumConfig{
+		threshold: len(members)/2 + 1,
+		grace:     DefaultQuorumGrace,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.threshold < 1 || cfg.threshold > len(members) {
+		return nil, fmt.Errorf("%w: %d of %d servers", ErrInvalidThreshold, cfg.threshold, len(members))
+	}
+
+	return &Quorum{
+		members:   members,
+		threshold: cfg.threshold,
+		grace:     cfg.grace,
+		onDissent: cfg.onDissent,
+	}, nil
+}
+
+// Members returns the members of the quorum.
+func (q *Quorum) Members() []Member {
+	return q.members
+}
+
+// Shutdown shuts all members down.
+func (q *Quorum) Shutdown() {
+	for _, m := range q.members {
+		m.Client.Shutdown()
+	}
+}
+
+type quorumResult[T any] struct {
+	index  int
+	result T
+	key    []byte
+	err    error
+}
+
+// quorumCall runs f on all members and returns the first result threshold members
+// agree on. Results are compared by their JSON encoding. The remaining members get
+// the grace period to answer, those disagreeing are reported.
+func quorumCall[T any](ctx context.Context, q *Quorum, method string, f func(ctx context.Context, c *Client) (T, error)) (T, error) {
+	// the late calls outlive ctx once the result has been returned
+	callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancel)
+
+	results := make(chan quorumResult[T], len(q.members))
+
+	for i, m := range q.members {
+		go func() {
+			result, err := f(callCtx, m.Client)
+
+			var key []byte
+			if err == nil {
+				key, err = json.Marshal(result)
+			}
+
+			results <- quorumResult[T]{index: i, result: result, key: key, err: err}
+		}()
+	}
+
+	answers := make([]QuorumAnswer, 0, len(q.members))
+	var received []quorumResult[T]
+
+	for range q.members {
+		r := <-results
+
+		answers = append(answers, QuorumAnswer{
+			Server: q.members[r.index].Name,
+			Result: r.result,
+			Err:    r.err,
+		})
+
+		if r.err != nil {
+			continue
+		}
+
+		received = append(received, r)
+
+		votes := 0
+		for _, other := range received {
+			if bytes.Equal(other.key, r.key) {
+				votes++
+			}
+		}
+
+		if votes >= q.threshold {
+			stop()
+
+			go func() {
+				defer cancel()
+
+				timer := time.AfterFunc(q.grace, cancel)
+				defer timer.Stop()
+
+				for range len(q.members) - len(answers) {
+					if late := <-results; late.err == nil {
+						received = append(received, late)
+					}
+				}
+
+				var dissent []QuorumAnswer
+				for _, other := range received {
+					if !bytes.Equal(other.key, r.key) {
+						dissent = append(dissent, QuorumAnswer{Server: q.members[other.index].Name, Result: other.result})
+					}
+				}
+
+				q.reportDissent(ctx, method, dissent)
+			}()
+
+			return r.result, nil
+		}
+	}
+
+	stop()
+	cancel()
+
+	var zero T
+	return zero, &DisagreementError{
+		Method:    method,
+		Threshold: q.threshold,
+		Answers:   answers,
+	}
+}
+
+// reportDissent passes the answers of the members disagreeing with the result of
+// method to the dissent handler or logs them.
+func (q *Quorum) reportDissent(ctx context.Context, method string, dissent []QuorumAnswer) {
+	if len(dissent) == 0 {
+		return
+	}
+
+	if q.onDissent != nil {
+		q.onDissent(method, dissent)
+		return
+	}
+
+	answers := make([]string, len(dissent))
+	for i, a := range dissent {
+		answers[i] = a.String()
+	}
+
+	logger.GetLogger(ctx).Warnf("Quorum members disagree on %s: %s", method, strings.Join(answers, ", "))
+}
+
+// GetBalance returns the confirmed and unconfirmed balance for a scripthash.
+func (q *Quorum) GetBalance(ctx context.Context, scripthash string) (GetBalanceResult, error) {
+	return quorumCall(ctx, q, "blockchain.scripthash.get_balance", func(ctx context.Context, c *Client) (GetBalanceResult, error) {
+		return c.GetBalance(ctx, scripthash)
+	})
+}
+
+// GetHistory returns the confirmed and unconfirmed history for a scripthash.
+func (q *Quorum) GetHistory(ctx context.Context, scripthash string) ([]*GetMempoolResult, error) {
+	return quorumCall(ctx, q, "blockchain.scripthash.get_history", func(ctx context.Context, c *Client) ([]*GetMempoolResult, error) {
+		return c.GetHistory(ctx, scripthash)
+	})
+}
+
+// ListUnspent returns an ordered list of UTXOs for a scripthash, optionally filtered
+// by their CashTokens.
+func (q *Quorum) ListUnspent(ctx context.Context, scripthash string, tokenFilter ...TokenFilter) ([]*ListUnspentResult, error) {
+	return quorumCall(ctx, q, "blockchain.scripthash.listunspent", func(ctx context.Context, c *Client) ([]*ListUnspentResult, error) {
+		return c.ListUnspent(ctx, scripthash, tokenFilter...)
+	})
+}
+
+// GetRawTransaction gets a raw encoded transaction.
+func (q *Quorum) GetRawTransaction(ctx context.Context, txHash string) (string, error) {
+	return quorumCall(ctx, q, "blockchain.transaction.get", func(ctx context.Context, c *Client) (string, error) {
+		return c.GetRawTransaction(ctx, txHash)
+	})
+}
+
+// GetMerkleProof returns the merkle proof for a confirmed transaction.
+func (q *Quorum) GetMerkleProof(ctx context.Context, txHash string, height uint32) (*GetMerkleProofResult, error) {
+	return quorumCall(ctx, q, "blockchain.transaction.get_merkle", func(ctx context.Context, c *Client) (*GetMerkleProofResult, error) {
+		return c.GetMerkleProof(ctx, txHash, height)
+	})
+}
+
+// GetBlockHeader returns the block header at a specific height.
+func (q *Quorum) GetBlockHeader(ctx context.Context, height uint32, checkpointHeight ...uint32) (*GetBlockHeaderResult, error) {
+	return quorumCall(ctx, q, "blockchain.block.header", func(ctx context.Context, c *Client) (*GetBlockHeaderResult, error) {
+		return c.GetBlockHeader(ctx, height, checkpointHeight...)
+	})
+}
+
+// GetBlockHeaders return a concatenated chunk of block headers.
+func (q *Quorum) GetBlockHeaders(ctx context.Context, startHeight, count uint32, checkpointHeight ...uint32) (*GetBlockHeadersResult, error) {
+	return quorumCall(ctx, q, "blockchain.block.headers", func(ctx context.Context, c *Client) (*GetBlockHeadersResult, error) {
+		return c.GetBlockHeaders(ctx, startHeight, count, checkpointHeight...)
+	})
+}
@@ -0,0 +1,133 @@
+package electrum_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// newQuorumMember creates a client answering every request with result.
+func newQuorumMember(t *testing.T, name string, result any) electrum.Member {
+	t.Helper()
+
+	transport := NewMockTransport()
+	stop := startAutoResponder(transport, result)
+	t.Cleanup(stop)
+
+	client := electrum.NewClient(context.Background(), transport)
+	t.Cleanup(client.Shutdown)
+
+	return electrum.Member{Name: name, Client: client}
+}
+
+func TestQuorum_Agree(t *testing.T) {
+	ctx := context.Background()
+
+	honest := map[string]any{"confirmed": 100, "unconfirmed": 0}
+
+	quorum, err := electrum.NewQuorum([]electrum.Member{
+		newQuorumMember(t, "a", honest),
+		newQuorumMember(t, "b", map[string]any{"confirmed": 999, "unconfirmed": 0}),
+		newQuorumMember(t, "c", honest),
+	})
+	require.NoError(t, err)
+
+	balance, err := quorum.GetBalance(ctx, "sh")
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 100}, balance)
+}
+
+func TestQuorum_LateDissent(t *testing.T) {
+	ctx := context.Background()
+
+	honest := map[string]any{"confirmed": 100, "unconfirmed": 0}
+
+	// the liar answers after the quorum has agreed
+	liar := NewMockTransport()
+	client := electrum.NewClient(ctx, liar)
+	defer client.Shutdown()
+
+	reports := make(chan []electrum.QuorumAnswer, 1)
+	quorum, err := electrum.NewQuorum([]electrum.Member{
+		newQuorumMember(t, "a", honest),
+		newQuorumMember(t, "b", honest),
+		{Name: "liar", Client: client},
+	}, electrum.WithDissentHandler(func(method string, dissent []electrum.QuorumAnswer) {
+		assert.Equal(t, "blockchain.scripthash.get_balance", method)
+		reports <- dissent
+	}))
+	require.NoError(t, err)
+
+	balance, err := quorum.GetBalance(ctx, "sh")
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 100}, balance)
+
+	stop := startAutoResponder(liar, map[string]any{"confirmed": 999, "unconfirmed": 0})
+	defer stop()
+
+	select {
+	case dissent := <-reports:
+		assert.Equal(t, []electrum.QuorumAnswer{
+			{Server: "liar", Result: electrum.GetBalanceResult{Confirmed: 999}},
+		}, dissent)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "dissent not reported")
+	}
+}
+
+func TestQuorum_Disagree(t *testing.T) {
+	ctx := context.Background()
+
+	quorum, err := electrum.NewQuorum([]electrum.Member{
+		newQuorumMember(t, "a", map[string]any{"confirmed": 1}),
+		newQuorumMember(t, "b", map[string]any{"confirmed": 2}),
+		newQuorumMember(t, "c", map[string]any{"confirmed": 3}),
+	})
+	require.NoError(t, err)
+
+	_, err = quorum.GetBalance(ctx, "sh")
+	require.ErrorIs(t, err, electrum.ErrDisagreement)
+
+	var disagreement *electrum.DisagreementError
+	require.ErrorAs(t, err, &disagreement)
+	assert.Equal(t, "blockchain.scripthash.get_balance", disagreement.Method)
+	assert.Equal(t, 2, disagreement.Threshold)
+	require.Len(t, disagreement.Answers, 3)
+
+	answers := make(map[string]float64)
+	for _, a := range disagreement.Answers {
+		require.NoError(t, a.Err)
+		answers[a.Server] = a.Result.(electrum.GetBalanceResult).Confirmed
+	}
+
+	assert.Equal(t, map[string]float64{"a": 1, "b": 2, "c": 3}, answers)
+}
+
+func TestQuorum_Threshold(t *testing.T) {
+	_, err := electrum.NewQuorum([]electrum.Member{
+		newQuorumMember(t, "a", nil),
+	}, electrum.WithThreshold(2))
+	assert.ErrorIs(t, err, electrum.ErrInvalidThreshold)
+
+	_, err = electrum.NewQuorum(nil)
+	assert.ErrorIs(t, err, electrum.ErrNoServer)
+
+	// a failing server does not count
+	failed := newQuorumMember(t, "failed", nil)
+	failed.Client.Shutdown()
+
+	quorum, err := electrum.NewQuorum([]electrum.Member{
+		newQuorumMember(t, "a", "00"),
+		failed,
+	}, electrum.WithThreshold(2))
+	require.NoError(t, err)
+
+	_, err = quorum.GetRawTransaction(context.Background(), "tx")
+	var disagreement *electrum.DisagreementError
+	require.ErrorAs(t, err, &disagreement)
+	assert.Len(t, disagreement.Answers, 2)
+}
@@ -51,15 +51,20 @@ func startAutoResponder(transport *MockTransport, result any) func() {
 				return
 			case msg := <-transport.sent():
 				var req struct {
-					ID uint64 `json:"id"`
+					ID     uint64 `json:"id"`
+					Method string `json:"method"`
 				}
 				if err := json.Unmarshal(msg, &req); err != nil {
 					return
 				}
+				var reply any = result
+				if req.Method == "server.version" {
+					reply = []string{"mock", electrum.ProtocolMax}
+				}
 				resp, _ := json.Marshal(map[string]any{
 					"jsonrpc": "2.0",
 					"id":      req.ID,
-					"result":  result,
+					"result":  reply,
 				})
 				transport.responses() <- resp
 			}
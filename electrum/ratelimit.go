@@ -0,0 +1,255 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// errExcessiveResourceUsage is the error code of ElectrumX if a session exceeded its cost limit.
+	errExcessiveResourceUsage = -101
+
+	// errServerBusy is the error code of ElectrumX if it is too busy to answer.
+	errServerBusy = -102
+
+	// maxThrottleLevel caps how often the rate is halved after throttling errors.
+	maxThrottleLevel = 6
+
+	// throttleRecovery is the number of successful requests after which the rate
+	// is doubled again.
+	throttleRecovery = 10
+)
+
+// ErrThrottled throws an error if the server refused a request because the client
+// sent too many or too expensive requests.
+var ErrThrottled = errors.New("server is throttling requests")
+
+// DefaultMethodCosts are the default rate limiter costs of methods which are more
+// expensive for the server than a simple call with a cost of 1. Methods with a
+// cost of 0 are neither rate limited nor counted as in flight.
+var DefaultMethodCosts = map[string]float64{
+	"server.ping":                       0,
+	"server.version":                    0,
+	"blockchain.block.headers":          4,
+	"blockchain.scripthash.get_history": 4,
+	"blockchain.scripthash.get_mempool": 2,
+	"blockchain.scripthash.listunspent": 4,
+	"blockchain.transaction.get_merkle": 2,
+}
+
+// WithMaxInFlight limits the number of requests waiting for a response. Further
+// requests wait until one of them has been answered. A batch counts as one request.
+func WithMaxInFlight(n int) ClientOption {
+	return func(c *clientConfig) {
+		c.maxInFlight = n
+	}
+}
+
+// WithRateLimit limits the cost of requests sent per second with a token bucket
+// holding up to burst tokens. Every request takes the cost of its method, see
+// WithMethodCost.
+func WithRateLimit(rate float64, burst int) ClientOption {
+	return func(c *clientConfig) {
+		c.rate = rate
+		c.burst = max(burst, 1)
+	}
+}
+
+// WithMethodCost sets the rate limiter cost of method.
+func WithMethodCost(method string, cost float64) ClientOption {
+	return func(c *clientConfig) {
+		c.methodCosts[method] = cost
+	}
+}
+
+// methodCost returns the rate limiter cost of method.
+func (c *clientConfig) methodCost(method string) float64 {
+	if cost, ok := c.methodCosts[method]; ok {
+		return cost
+	}
+
+	return 1
+}
+
+// IsThrottled reports whether err is a throttling error of the server, e.g.
+// "excessive resource usage".
+func IsThrottled(err error) bool {
+	if errors.Is(err, ErrThrottled) {
+		return true
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.Code {
+	case errExcessiveResourceUsage, errServerBusy:
+		return true
+	}
+
+	return strings.Contains(strings.ToLower(apiErr.Message), "excessive resource usage")
+}
+
+// limiter caps the requests in flight and the rate of requests. After throttling
+// errors of the server it pauses all requests and halves the rate until enough
+// requests have succeeded again.
+type limiter struct {
+	inFlight chan struct{}
+	rate     float64
+	burst    float64
+	backoff  Backoff
+
+	lock      sync.Mutex
+	tokens    float64
+	last      time.Time
+	level     int
+	successes int
+	paused    time.Time
+}
+
+func newLimiter(cfg *clientConfig) *limiter {
+	l := &limiter{
+		rate:    cfg.rate,
+		burst:   float64(cfg.burst),
+		backoff: cfg.backoff,
+		tokens:  float64(cfg.burst),
+		last:    time.Now(),
+	}
+
+	if cfg.maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, cfg.maxInFlight)
+	}
+
+	return l
+}
+
+// acquire waits until a request of cost may be sent. The returned func releases
+// the in-flight slot.
+func (l *limiter) acquire(ctx context.Context, quit <-chan struct{}, cost float64) (func(), error) {
+	if cost <= 0 {
+		return func() {}, nil
+	}
+
+	if err := l.wait(ctx, quit, cost); err != nil {
+		return nil, err
+	}
+
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, nil
+	case <-quit:
+		return nil, ErrServerShutdown
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// wait takes cost tokens from the bucket, it waits while the client is paused
+// after a throttling error or the bucket holds too few tokens.
+func (l *limiter) wait(ctx context.Context, quit <-chan struct{}, cost float64) error {
+	for {
+		delay := l.reserve(cost)
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-quit:
+			timer.Stop()
+			return ErrServerShutdown
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes cost tokens and returns zero or the time to wait before trying again.
+func (l *limiter) reserve(cost float64) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Before(l.paused) {
+		return l.paused.Sub(now)
+	}
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	rate := l.rate / math.Pow(2, float64(l.level))
+
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*rate)
+	l.last = now
+
+	cost = min(cost, l.burst)
+	if l.tokens >= cost {
+		l.tokens -= cost
+		return 0
+	}
+
+	return time.Duration((cost - l.tokens) / rate * float64(time.Second))
+}
+
+// throttled pauses all requests and halves the rate.
+func (l *limiter) throttled() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	pause := l.backoff.Duration(l.level)
+
+	l.level = min(l.level+1, maxThrottleLevel)
+	l.successes = 0
+	l.paused = time.Now().Add(pause)
+	l.tokens = 0
+
+	return pause
+}
+
+// succeeded doubles the rate again after enough successful requests.
+func (l *limiter) succeeded() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.level == 0 {
+		return
+	}
+
+	l.successes++
+	if l.successes >= throttleRecovery {
+		l.level--
+		l.successes = 0
+	}
+}
+
+// checkThrottled makes the client back off if err is a throttling error and
+// returns err wrapped into ErrThrottled.
+func (s *Client) checkThrottled(method string, err error) error {
+	if err == nil {
+		s.limiter.succeeded()
+		return nil
+	}
+
+	if !IsThrottled(err) {
+		return err
+	}
+
+	pause := s.limiter.throttled()
+	s.log.Warnf("Server throttles %s, pausing requests for %v: %v", method, pause, err)
+
+	return fmt.Errorf("%w: %w", ErrThrottled, err)
+}
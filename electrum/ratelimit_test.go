@@ -0,0 +1,153 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestRateLimit_MaxInFlight(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake(), electrum.WithMaxInFlight(2))
+	defer client.Shutdown()
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.ServerBanner(ctx)
+		}()
+	}
+
+	var ids []uint64
+	for range 2 {
+		select {
+		case msg := <-transport.sent():
+			var req struct {
+				ID uint64 `json:"id"`
+			}
+			require.NoError(t, json.Unmarshal(msg, &req))
+			ids = append(ids, req.ID)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no request received")
+		}
+	}
+
+	select {
+	case <-transport.sent():
+		require.FailNow(t, "third request sent while two are in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	resp, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": ids[0], "result": "banner"})
+	require.NoError(t, err)
+	transport.responses() <- resp
+
+	assert.Equal(t, "server.banner", respond(t, transport, func(string) any { return "banner" }))
+
+	resp, err = json.Marshal(map[string]any{"jsonrpc": "2.0", "id": ids[1], "result": "banner"})
+	require.NoError(t, err)
+	transport.responses() <- resp
+
+	wg.Wait()
+}
+
+func TestRateLimit_Rate(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	stop := startAutoResponder(transport, "banner")
+	defer stop()
+
+	client := electrum.NewClient(ctx, transport, electrum.WithRateLimit(20, 1))
+	defer client.Shutdown()
+
+	start := time.Now()
+	for range 5 {
+		_, err := client.ServerBanner(ctx)
+		require.NoError(t, err)
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	// pings are free
+	start = time.Now()
+	for range 5 {
+		require.NoError(t, client.Ping(ctx))
+	}
+
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestRateLimit_Throttled(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport,
+		electrum.WithoutHandshake(),
+		electrum.WithBackoff(electrum.Backoff{Min: 200 * time.Millisecond}),
+	)
+	defer client.Shutdown()
+
+	go func() {
+		msg := <-transport.sent()
+
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		json.Unmarshal(msg, &req)
+
+		resp, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"error":   map[string]any{"code": -101, "message": "excessive resource usage"},
+		})
+		transport.responses() <- resp
+	}()
+
+	_, err := client.ServerBanner(ctx)
+	assert.ErrorIs(t, err, electrum.ErrThrottled)
+
+	var apiErr *electrum.APIError
+	assert.ErrorAs(t, err, &apiErr)
+
+	stop := startAutoResponder(transport, "banner")
+	defer stop()
+
+	start := time.Now()
+	_, err = client.ServerBanner(ctx)
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestIsThrottled(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"other", errors.New("excessive resource usage"), false},
+		{"code", &electrum.APIError{Code: -102, Message: "server busy"}, true},
+		{"message", &electrum.APIError{Message: "Excessive resource usage"}, true},
+		{"api", &electrum.APIError{Code: 1, Message: "unknown method"}, false},
+		{"throttled", electrum.ErrThrottled, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, electrum.IsThrottled(tt.err))
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package electrum
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// DialFunc opens a new transport to the remote server. A reconnecting client
+// calls it every time the current connection has to be replaced.
+type DialFunc func(ctx context.Context) (Transport, error)
+
+// DialTCP returns a DialFunc connecting to addr using TCP.
+func DialTCP(addr string, opts ...TransportOption) DialFunc {
+	return func(ctx context.Context) (Transport, error) {
+		return NewTCPTransport(ctx, addr, opts...)
+	}
+}
+
+// DialSSL returns a DialFunc connecting to addr using SSL.
+func DialSSL(addr string, config *tls.Config, opts ...TransportOption) DialFunc {
+	return func(ctx context.Context) (Transport, error) {
+		return NewSSLTransport(ctx, addr, config, opts...)
+	}
+}
+
+// Backoff describes an exponential backoff with jitter.
+type Backoff struct {
+	// Min is the delay before the first attempt.
+	Min time.Duration
+	// Max caps the delay between two attempts.
+	Max time.Duration
+	// Factor multiplies the delay after every failed attempt.
+	Factor float64
+	// Jitter randomizes the delay by +/- the given fraction (0..1).
+	Jitter float64
+}
+
+// DefaultBackoff is the reconnect policy used when no other one is configured.
+var DefaultBackoff = Backoff{
+	Min:    500 * time.Millisecond,
+	Max:    30 * time.Second,
+	Factor: 2,
+	Jitter: 0.2,
+}
+
+// Duration returns the delay before the given attempt, counting from zero.
+func (b Backoff) Duration(attempt int) time.Duration {
+	d := float64(b.Min)
+	if b.Factor > 1 {
+		d *= math.Pow(b.Factor, float64(attempt))
+	}
+
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// NewReconnectingClient dials the remote server and returns a client that
+// transparently reconnects through dial whenever the connection is lost.
+func NewReconnectingClient(ctx context.Context, dial DialFunc, opts ...ClientOption) (*Client, error) {
+	transport, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]ClientOption{WithReconnect(dial)}, opts...)
+
+	return NewClient(ctx, transport, opts...), nil
+}
+
+// subscription is an active server subscription that has to be renewed after
+// a reconnect.
+type subscription struct {
+	id    uint64
+	renew func(ctx context.Context) error
+}
+
+func (s *Client) addSubscription(renew func(ctx context.Context) error) uint64 {
+	sub := &subscription{
+		id:    atomic.AddUint64(&s.nextSubscriptionID, 1),
+		renew: renew,
+	}
+
+	s.subscriptions.Change(func(val []*subscription) ([]*subscription, error) {
+		return append(val, sub), nil
+	})
+
+	return sub.id
+}
+
+func (s *Client) removeSubscription(id uint64) {
+	s.subscriptions.Change(func(val []*subscription) ([]*subscription, error) {
+		return slices.DeleteFunc(val, func(sub *subscription) bool {
+			return sub.id == id
+		}), nil
+	})
+}
+
+// reconnect replaces the broken transport with a new one and restores the
+// session in the background. It returns when a new transport is in place or
+// the client has been shut down.
+func (s *Client) reconnect(cause error) {
+	s.log.Warnf("Connection lost: %v", cause)
+
+	s.transport.Do(func(val Transport) error {
+		if val != nil {
+			val.Close()
+		}
+
+		return nil
+	})
+
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-s.quit:
+			return
+		case <-time.After(s.config.backoff.Duration(attempt)):
+		}
+
+		transport, err := s.config.dial(s.ctx)
+		if err != nil {
+			s.log.Warnf("Reconnect attempt %d failed: %v", attempt+1, err)
+			continue
+		}
+
+		var handshake chan struct{}
+		if s.config.handshake {
+			handshake = s.startHandshake()
+		} else {
+			s.negotiated.Reset()
+		}
+
+		err = s.transport.Change(func(val Transport) (Transport, error) {
+			if s.IsShutdown() {
+				return val, ErrServerShutdown
+			}
+
+			return transport, nil
+		})
+
+		if err != nil {
+			transport.Close()
+			return
+		}
+
+		s.connectedAt.Store(time.Now().UnixNano())
+		s.failures.Store(0)
+		s.generation.Add(1)
+		s.setState(StateConnected, "", nil)
+		go s.restore(handshake)
+
+		return
+	}
+}
+
+// restore repeats the handshake and renews all subscriptions on a new connection.
+func (s *Client) restore(handshake chan struct{}) {
+	if err := s.handshake(handshake); err != nil {
+		return
+	}
+
+	subscriptions, _ := Get(s.subscriptions, func(val []*subscription) ([]*subscription, bool) {
+		return slices.Clone(val), true
+	})
+
+	// a failed or stalled renewal must not hold back the others
+	timeout := s.config.timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	for _, sub := range subscriptions {
+		ctx, cancel := context.WithTimeout(s.ctx, timeout)
+		err := sub.renew(ctx)
+		cancel()
+
+		if err != nil {
+			s.log.Errorf("Resubscribe after reconnect failed: %v", err)
+		}
+	}
+}
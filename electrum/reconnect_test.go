@@ -0,0 +1,239 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+var testBackoff = electrum.Backoff{
+	Min:    time.Millisecond,
+	Max:    10 * time.Millisecond,
+	Factor: 2,
+}
+
+// dialSequence returns a DialFunc handing out the given transports one after
+// another and failing once they are used up.
+func dialSequence(transports ...electrum.Transport) electrum.DialFunc {
+	ch := make(chan electrum.Transport, len(transports))
+	for _, t := range transports {
+		ch <- t
+	}
+
+	return func(ctx context.Context) (electrum.Transport, error) {
+		select {
+		case t := <-ch:
+			return t, nil
+		default:
+			return nil, errors.New("no transport left")
+		}
+	}
+}
+
+// respond answers the next request sent on transport with the result returned
+// by f for the request method.
+func respond(t *testing.T, transport *MockTransport, f func(method string) any) string {
+	t.Helper()
+
+	var msg []byte
+	select {
+	case msg = <-transport.sent():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request received")
+	}
+
+	var req struct {
+		ID     uint64 `json:"id"`
+		Method string `json:"method"`
+	}
+	require.NoError(t, json.Unmarshal(msg, &req))
+
+	resp, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": f(req.Method)})
+	require.NoError(t, err)
+
+	transport.responses() <- resp
+
+	return req.Method
+}
+
+func TestBackoff_Duration(t *testing.T) {
+	b := electrum.Backoff{Min: 100 * time.Millisecond, Max: time.Second, Factor: 2}
+
+	assert.Equal(t, 100*time.Millisecond, b.Duration(0))
+	assert.Equal(t, 400*time.Millisecond, b.Duration(2))
+	assert.Equal(t, time.Second, b.Duration(10))
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.Duration(1)
+		assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		assert.LessOrEqual(t, d, 300*time.Millisecond)
+	}
+}
+
+func TestReconnect_RestoresSubscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	first := NewMockTransport()
+	second := NewMockTransport()
+
+	client := electrum.NewClient(ctx, first, electrum.WithoutHandshake(), electrum.WithReconnect(dialSequence(second)), electrum.WithBackoff(testBackoff))
+	defer client.Shutdown()
+
+	var headerChan <-chan *electrum.SubscribeHeadersResult
+	err := first.exec(1, &electrum.SubscribeHeadersResult{Height: 1, Hex: "aa"}, func() error {
+		ch, err := client.SubscribeHeaders(ctx)
+		headerChan = ch
+		return err
+	})
+	require.NoError(t, err)
+	<-headerChan
+
+	sub, notifChan := client.SubscribeScripthash()
+	err = first.exec(2, "status1", func() error {
+		return sub.Add(ctx, "sh1")
+	})
+	require.NoError(t, err)
+	<-notifChan
+
+	var mnChan <-chan string
+	err = first.exec(3, "ENABLED", func() error {
+		ch, err := client.SubscribeMasternode(ctx, "collateral")
+		mnChan = ch
+		return err
+	})
+	require.NoError(t, err)
+	<-mnChan
+
+	first.fail(io.EOF)
+
+	results := map[string]any{
+		"server.version":                  [2]string{"ElectrumX 1.16.0", "1.4"},
+		"blockchain.headers.subscribe":    &electrum.SubscribeHeadersResult{Height: 2, Hex: "bb"},
+		"blockchain.scripthash.subscribe": "status2",
+		"blockchain.masternode.subscribe": "POSE_BANNED",
+	}
+
+	var methods []string
+	for range results {
+		methods = append(methods, respond(t, second, func(method string) any {
+			return results[method]
+		}))
+	}
+
+	assert.Equal(t, []string{
+		"server.version",
+		"blockchain.headers.subscribe",
+		"blockchain.scripthash.subscribe",
+		"blockchain.masternode.subscribe",
+	}, methods)
+
+	assert.Equal(t, &electrum.SubscribeHeadersResult{Height: 2, Hex: "bb"}, <-headerChan)
+	assert.Equal(t, [2]string{"sh1", "status2"}, (<-notifChan).Params)
+	assert.Equal(t, "POSE_BANNED", <-mnChan)
+	assert.Equal(t, []string{"sh1"}, sub.SH())
+
+	// Existing channels keep receiving notifications from the new connection.
+	require.NoError(t, second.notify("blockchain.headers.subscribe", []*electrum.SubscribeHeadersResult{{Height: 3, Hex: "cc"}}))
+	assert.Equal(t, &electrum.SubscribeHeadersResult{Height: 3, Hex: "cc"}, <-headerChan)
+	assert.False(t, client.IsShutdown())
+}
+
+func TestReconnect_UndrainedSubscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	first := NewMockTransport()
+	second := NewMockTransport()
+
+	client := electrum.NewClient(ctx, first, electrum.WithoutHandshake(), electrum.WithReconnect(dialSequence(second)), electrum.WithBackoff(testBackoff))
+	defer client.Shutdown()
+
+	// the consumer never reads the first status, the channel stays full
+	var mnChan <-chan string
+	err := first.exec(1, "ENABLED", func() error {
+		ch, err := client.SubscribeMasternode(ctx, "collateral")
+		mnChan = ch
+		return err
+	})
+	require.NoError(t, err)
+
+	sub, notifChan := client.SubscribeScripthash()
+	err = first.exec(2, "status1", func() error {
+		return sub.Add(ctx, "sh1")
+	})
+	require.NoError(t, err)
+
+	first.fail(io.EOF)
+
+	results := map[string]any{
+		"server.version":                  [2]string{"ElectrumX 1.16.0", "1.4"},
+		"blockchain.masternode.subscribe": "POSE_BANNED",
+		"blockchain.scripthash.subscribe": "status2",
+	}
+
+	// the stalled masternode renewal must not hold back the scripthash renewal
+	var methods []string
+	for range results {
+		methods = append(methods, respond(t, second, func(method string) any {
+			return results[method]
+		}))
+	}
+
+	assert.Equal(t, []string{
+		"server.version",
+		"blockchain.masternode.subscribe",
+		"blockchain.scripthash.subscribe",
+	}, methods)
+
+	// the stale status was replaced by the current one
+	require.Eventually(t, func() bool { return len(notifChan) == 2 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, "POSE_BANNED", <-mnChan)
+	assert.Equal(t, [2]string{"sh1", "status1"}, (<-notifChan).Params)
+	assert.Equal(t, [2]string{"sh1", "status2"}, (<-notifChan).Params)
+	assert.False(t, client.IsShutdown())
+}
+
+func TestReconnect_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+
+	first := NewMockTransport()
+	second := NewMockTransport()
+
+	attempts := 0
+	dial := func(ctx context.Context) (electrum.Transport, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection refused")
+		}
+
+		return second, nil
+	}
+
+	client := electrum.NewClient(ctx, first, electrum.WithReconnect(dial), electrum.WithBackoff(testBackoff))
+	defer client.Shutdown()
+
+	first.fail(io.EOF)
+
+	respond(t, second, func(method string) any {
+		return [2]string{"ElectrumX 1.16.0", "1.4"}
+	})
+
+	assert.Equal(t, 3, attempts)
+	assert.False(t, client.IsShutdown())
+}
+
+func TestClient_ShutdownWithoutReconnect(t *testing.T) {
+	ctx := context.Background()
+	client, transport := newTestClient(ctx, t)
+
+	transport.fail(io.EOF)
+
+	require.Eventually(t, client.IsShutdown, 2*time.Second, 5*time.Millisecond)
+}
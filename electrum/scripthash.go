@@ -32,7 +32,9 @@ type GetMempoolResp struct {
 }
 
 // GetMempoolResult represents the content of the result field in the response
-// to GetHistory() and GetMempool().
+// to GetHistory() and GetMempool(). The history has no CashTokens, servers add
+// token_data only to unspent outputs. GetTransaction returns the tokens of the
+// outputs of a transaction.
 type GetMempoolResult struct {
 	Hash   string `json:"tx_hash"`
 	Height int32  `json:"height"`
@@ -74,10 +76,17 @@ type ListUnspentResult struct {
 	Position uint32 `json:"tx_pos"`
 	Hash     string `json:"tx_hash"`
 	Value    uint64 `json:"value"`
+
+	// TokenData is set if the UTXO carries CashTokens. The servers name the field
+	// token_data here, unlike the tokenData of the node in verbose transactions.
+	TokenData *TokenData `json:"token_data,omitempty"`
 }
 
-// ListUnspent returns an ordered list of UTXOs for a scripthash.
-func (s *Client) ListUnspent(ctx context.Context, scripthash string) ([]*ListUnspentResult, error) {
+// ListUnspent returns an ordered list of UTXOs for a scripthash. The optional
+// tokenFilter selects the UTXOs by their CashTokens, use TokenFilterExclude to get
+// only the UTXOs which can be spent as plain coins. The filter is applied by the
+// client, it works with servers unaware of CashTokens too.
+func (s *Client) ListUnspent(ctx context.Context, scripthash string, tokenFilter ...TokenFilter) ([]*ListUnspentResult, error) {
 	var resp ListUnspentResp
 
 	err := s.request(ctx, "blockchain.scripthash.listunspent", []interface{}{scripthash}, &resp)
@@ -85,5 +94,9 @@ func (s *Client) ListUnspent(ctx context.Context, scripthash string) ([]*ListUns
 		return nil, err
 	}
 
+	if len(tokenFilter) > 0 {
+		return filterTokens(resp.Result, tokenFilter[0]), nil
+	}
+
 	return resp.Result, err
 }
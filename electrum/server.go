@@ -1,6 +1,9 @@
 package electrum
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 // Ping send a ping to the target server to ensure it is responding and
 // keeping the session alive.
@@ -15,9 +18,8 @@ func (s *Client) Ping(ctx context.Context) error {
 // This should not be used if you are a client.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#server-add-peer
 func (s *Client) ServerAddPeer(ctx context.Context, features *ServerFeaturesResult) error {
-	var resp BasicResp
-
-	err := s.request(ctx, "server.add_peer", []interface{}{features}, &resp)
+	// the result is a bool, not a string
+	err := s.request(ctx, "server.add_peer", []interface{}{features}, nil)
 
 	return err
 }
@@ -134,19 +136,69 @@ type ServerVersionResp struct {
 }
 
 // ServerVersion identify the client to the server, and negotiate the protocol version.
-// This call must be sent first, or the server will default to an older protocol version.
+// The client negotiates the range set by WithProtocolVersion on every new connection,
+// so the call returns the remembered result or the error of the failed handshake
+// without asking the server again. protocol
+// is an optional single version or min and max version. If the version negotiated on
+// the connection is outside of it, ServerVersion fails with ErrProtocolMismatch, since
+// servers accept server.version only once per connection.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#server-version
-func (s *Client) ServerVersion(ctx context.Context) (serverVer, protocolVer string, err error) {
+func (s *Client) ServerVersion(ctx context.Context, protocol ...string) (serverVer, protocolVer string, err error) {
+	minVersion, maxVersion := s.config.protocolMin, s.config.protocolMax
+	switch len(protocol) {
+	case 0:
+	case 1:
+		minVersion, maxVersion = protocol[0], protocol[0]
+	default:
+		minVersion, maxVersion = protocol[0], protocol[1]
+	}
+
+	if err := s.waitHandshake(ctx); err != nil {
+		return "", "", err
+	}
+
+	negotiated, ok := Get(s.negotiated, func(val [2]string) ([2]string, bool) {
+		return val, val[1] != ""
+	})
+
+	if !ok {
+		return s.negotiate(ctx, minVersion, maxVersion)
+	}
+
+	if !protocolInRange(negotiated[1], minVersion, maxVersion) {
+		return "", "", fmt.Errorf("%w: negotiated %s, requested %s to %s", ErrProtocolMismatch, negotiated[1], minVersion, maxVersion)
+	}
+
+	return negotiated[0], negotiated[1], nil
+}
+
+// negotiate sends server.version for the range minVersion to maxVersion and
+// remembers the result for the connection.
+func (s *Client) negotiate(ctx context.Context, minVersion, maxVersion string) (serverVer, protocolVer string, err error) {
+	var version any = minVersion
+	if minVersion != maxVersion {
+		version = []string{minVersion, maxVersion}
+	}
+
 	var resp ServerVersionResp
 
-	err = s.request(ctx, "server.version", []interface{}{ClientVersion, ProtocolVersion}, &resp)
+	err = s.request(ctx, "server.version", []interface{}{ClientVersion, version}, &resp)
 	if err != nil {
-		serverVer = ""
-		protocolVer = ""
-	} else {
-		serverVer = resp.Result[0]
-		protocolVer = resp.Result[1]
+		return "", "", err
 	}
 
+	serverVer = resp.Result[0]
+	protocolVer = resp.Result[1]
+
+	if !protocolInRange(protocolVer, minVersion, maxVersion) {
+		return "", "", fmt.Errorf("%w: server chose %s, offered %s to %s", ErrProtocolMismatch, protocolVer, minVersion, maxVersion)
+	}
+
+	s.negotiated.Change(func([2]string) ([2]string, error) {
+		return resp.Result, nil
+	})
+
+	s.setState(StateReady, protocolVer, nil)
+
 	return
 }
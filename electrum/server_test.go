@@ -54,6 +54,20 @@ func TestServerAddPeer(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestServerAddPeer_BoolResult(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, transport := newTestClient(ctx, t)
+	defer client.Shutdown()
+
+	// the server answers with a bool, not a string
+	err := transport.exec(1, true, func() error {
+		return client.ServerAddPeer(ctx, &electrum.ServerFeaturesResult{})
+	})
+	require.NoError(t, err)
+}
+
 func TestServerBanner(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
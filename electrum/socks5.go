@@ -0,0 +1,305 @@
+package electrum
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultTorProxy is the SOCKS5 address of a local Tor daemon.
+	DefaultTorProxy = "127.0.0.1:9050"
+
+	socks5Version     = 0x05
+	socks5AuthVersion = 0x01
+
+	socks5NoAuth       = 0x00
+	socks5UserPassAuth = 0x02
+	socks5NoAcceptable = 0xff
+
+	socks5Connect = 0x01
+
+	socks5IPv4   = 0x01
+	socks5Domain = 0x03
+	socks5IPv6   = 0x04
+)
+
+var (
+	// ErrSOCKS5Auth throws an error if the proxy rejected the credentials.
+	ErrSOCKS5Auth = errors.New("socks5 authentication failed")
+
+	// ErrSOCKS5Protocol throws an error if the proxy answered with an invalid message.
+	ErrSOCKS5Protocol = errors.New("socks5 protocol error")
+)
+
+// SOCKS5Error is returned if the proxy could not establish the connection.
+type SOCKS5Error struct {
+	Code byte
+}
+
+var socks5Errors = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+	0xf0: "onion service descriptor can not be found",
+	0xf1: "onion service descriptor is invalid",
+	0xf2: "onion service introduction failed",
+	0xf3: "onion service rendezvous failed",
+	0xf4: "onion service missing client authorization",
+	0xf5: "onion service wrong client authorization",
+	0xf6: "onion service invalid address",
+	0xf7: "onion service introduction timed out",
+}
+
+func (e *SOCKS5Error) Error() string {
+	if msg, ok := socks5Errors[e.Code]; ok {
+		return "socks5: " + msg
+	}
+
+	return fmt.Sprintf("socks5: unknown error %#02x", e.Code)
+}
+
+// SOCKS5Auth stores the username/password credentials for a SOCKS5 proxy.
+// Tor uses them to isolate streams, connections with different credentials
+// never share a circuit.
+type SOCKS5Auth struct {
+	Username string
+	Password string
+}
+
+// SOCKS5Dialer opens connections through a SOCKS5 proxy. Host names are resolved
+// by the proxy, so .onion addresses can be used with Tor.
+type SOCKS5Dialer struct {
+	// ProxyAddr is the host:port of the proxy.
+	ProxyAddr string
+	// Auth are the optional credentials for the proxy.
+	Auth *SOCKS5Auth
+	// IsolateStreams generates random credentials for every connection when
+	// Auth is nil, so Tor builds a separate circuit for each client.
+	IsolateStreams bool
+	// Forward is used to connect to the proxy, a net.Dialer if nil.
+	Forward Dialer
+}
+
+// NewSOCKS5Dialer returns a dialer using the SOCKS5 proxy at proxyAddr.
+func NewSOCKS5Dialer(proxyAddr string, auth *SOCKS5Auth) *SOCKS5Dialer {
+	return &SOCKS5Dialer{
+		ProxyAddr: proxyAddr,
+		Auth:      auth,
+	}
+}
+
+// NewTorDialer returns a dialer using the Tor SOCKS5 proxy at proxyAddr, or
+// DefaultTorProxy if empty, with a separate circuit for every connection.
+func NewTorDialer(proxyAddr string) *SOCKS5Dialer {
+	if proxyAddr == "" {
+		proxyAddr = DefaultTorProxy
+	}
+
+	return &SOCKS5Dialer{
+		ProxyAddr:      proxyAddr,
+		IsolateStreams: true,
+	}
+}
+
+// DialContext connects to addr through the proxy.
+func (d *SOCKS5Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("socks5: network %s is not supported", network)
+	}
+
+	forward := d.Forward
+	if forward == nil {
+		forward = &net.Dialer{}
+	}
+
+	conn, err := forward.DialContext(ctx, "tcp", d.ProxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+
+	err = d.connect(conn, addr)
+
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+func (d *SOCKS5Dialer) credentials() (*SOCKS5Auth, error) {
+	if d.Auth != nil || !d.IsolateStreams {
+		return d.Auth, nil
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	return &SOCKS5Auth{
+		Username: hex.EncodeToString(buf[:8]),
+		Password: hex.EncodeToString(buf[8:]),
+	}, nil
+}
+
+func (d *SOCKS5Dialer) connect(conn net.Conn, addr string) error {
+	auth, err := d.credentials()
+	if err != nil {
+		return err
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("socks5: invalid port %s", portStr)
+	}
+
+	method := byte(socks5NoAuth)
+	if auth != nil {
+		method = socks5UserPassAuth
+	}
+
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+
+	if buf[0] != socks5Version {
+		return fmt.Errorf("%w: unexpected version %d", ErrSOCKS5Protocol, buf[0])
+	}
+
+	switch buf[1] {
+	case socks5NoAuth:
+	case socks5UserPassAuth:
+		if auth == nil {
+			return ErrSOCKS5Auth
+		}
+
+		if err := authenticate(conn, auth); err != nil {
+			return err
+		}
+	case socks5NoAcceptable:
+		return fmt.Errorf("%w: no acceptable authentication method", ErrSOCKS5Auth)
+	default:
+		return fmt.Errorf("%w: unexpected authentication method %d", ErrSOCKS5Protocol, buf[1])
+	}
+
+	req := []byte{socks5Version, socks5Connect, 0}
+
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("socks5: host name too long: %s", host)
+		}
+
+		req = append(req, socks5Domain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5IPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5IPv6)
+		req = append(req, ip.To16()...)
+	}
+
+	req = append(req, byte(port>>8), byte(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	buf = make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+
+	if buf[0] != socks5Version {
+		return fmt.Errorf("%w: unexpected version %d", ErrSOCKS5Protocol, buf[0])
+	}
+
+	if buf[1] != 0 {
+		return &SOCKS5Error{Code: buf[1]}
+	}
+
+	var size int
+	switch buf[3] {
+	case socks5IPv4:
+		size = net.IPv4len
+	case socks5IPv6:
+		size = net.IPv6len
+	case socks5Domain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		size = int(l[0])
+	default:
+		return fmt.Errorf("%w: unexpected address type %d", ErrSOCKS5Protocol, buf[3])
+	}
+
+	// skip bound address and port
+	_, err = io.ReadFull(conn, make([]byte, size+2))
+
+	return err
+}
+
+func authenticate(conn net.Conn, auth *SOCKS5Auth) error {
+	if len(auth.Username) > 255 || len(auth.Password) > 255 {
+		return fmt.Errorf("%w: credentials too long", ErrSOCKS5Auth)
+	}
+
+	req := []byte{socks5AuthVersion, byte(len(auth.Username))}
+	req = append(req, auth.Username...)
+	req = append(req, byte(len(auth.Password)))
+	req = append(req, auth.Password...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+
+	if buf[1] != 0 {
+		return ErrSOCKS5Auth
+	}
+
+	return nil
+}
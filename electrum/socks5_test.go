@@ -0,0 +1,225 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+type socks5Request struct {
+	Username string
+	Password string
+	Target   string
+}
+
+// socks5Proxy is a minimal SOCKS5 proxy resolving the host names listed in hosts.
+type socks5Proxy struct {
+	addr     string
+	hosts    map[string]string
+	userPass bool
+
+	lock     sync.Mutex
+	requests []socks5Request
+}
+
+func newSOCKS5Proxy(t *testing.T, userPass bool, hosts map[string]string) *socks5Proxy {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	p := &socks5Proxy{
+		addr:     l.Addr().String(),
+		hosts:    hosts,
+		userPass: userPass,
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go p.serve(conn)
+		}
+	}()
+
+	return p
+}
+
+func (p *socks5Proxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+
+	methods := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+
+	var req socks5Request
+
+	if p.userPass {
+		conn.Write([]byte{5, 2})
+
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		user := make([]byte, buf[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, buf[:1])
+		pass := make([]byte, buf[0])
+		io.ReadFull(conn, pass)
+
+		req.Username = string(user)
+		req.Password = string(pass)
+
+		if req.Password == "wrong" {
+			conn.Write([]byte{1, 1})
+			return
+		}
+
+		conn.Write([]byte{1, 0})
+	} else {
+		conn.Write([]byte{5, 0})
+	}
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return
+	}
+
+	var host string
+	switch head[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		io.ReadFull(conn, buf[:1])
+		name := make([]byte, buf[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+
+	port := make([]byte, 2)
+	io.ReadFull(conn, port)
+
+	req.Target = net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	p.lock.Lock()
+	p.requests = append(p.requests, req)
+	p.lock.Unlock()
+
+	target, ok := p.hosts[req.Target]
+	if !ok {
+		conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func (p *socks5Proxy) log() []socks5Request {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]socks5Request(nil), p.requests...)
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		return "hidden service"
+	})
+
+	proxy := newSOCKS5Proxy(t, true, map[string]string{"abcdef.onion:50001": addr})
+
+	dialer := electrum.NewSOCKS5Dialer(proxy.addr, &electrum.SOCKS5Auth{Username: "user", Password: "secret"})
+
+	client, err := electrum.NewClientTCP(ctx, "abcdef.onion:50001",
+		electrum.WithTransportOptions(electrum.WithDialer(dialer)))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "hidden service", banner)
+
+	assert.Equal(t, []socks5Request{{Username: "user", Password: "secret", Target: "abcdef.onion:50001"}}, proxy.log())
+}
+
+func TestSOCKS5Dialer_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	proxy := newSOCKS5Proxy(t, true, nil)
+
+	dialer := electrum.NewSOCKS5Dialer(proxy.addr, &electrum.SOCKS5Auth{Username: "user", Password: "wrong"})
+	_, err := dialer.DialContext(ctx, "tcp", "example.com:50001")
+	assert.ErrorIs(t, err, electrum.ErrSOCKS5Auth)
+
+	dialer = electrum.NewSOCKS5Dialer(proxy.addr, &electrum.SOCKS5Auth{Username: "user", Password: "secret"})
+	_, err = dialer.DialContext(ctx, "tcp", "unknown.onion:50001")
+
+	var socksErr *electrum.SOCKS5Error
+	require.True(t, errors.As(err, &socksErr))
+	assert.Equal(t, byte(4), socksErr.Code)
+	assert.Equal(t, "socks5: host unreachable", err.Error())
+
+	_, err = electrum.NewSOCKS5Dialer(proxy.addr, nil).DialContext(ctx, "tcp", "example.com:50001")
+	assert.ErrorIs(t, err, electrum.ErrSOCKS5Auth)
+}
+
+func TestTorDialer_IsolateStreams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		return nil
+	})
+
+	proxy := newSOCKS5Proxy(t, true, map[string]string{"abcdef.onion:50001": addr})
+	dialer := electrum.NewTorDialer(proxy.addr)
+
+	for i := 0; i < 2; i++ {
+		client, err := electrum.NewClientTCP(ctx, "abcdef.onion:50001",
+			electrum.WithTransportOptions(electrum.WithDialer(dialer)))
+		require.NoError(t, err)
+		require.NoError(t, client.Ping(ctx))
+		client.Shutdown()
+	}
+
+	log := proxy.log()
+	require.Len(t, log, 2)
+	assert.NotEmpty(t, log[0].Username)
+	assert.NotEmpty(t, log[0].Password)
+	assert.NotEqual(t, log[0].Username, log[1].Username)
+	assert.NotEqual(t, log[0].Password, log[1].Password)
+}
@@ -0,0 +1,220 @@
+package electrum
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// State is the connection state of a client.
+type State int
+
+const (
+	// StateConnected means the transport is connected, the server.version handshake
+	// has not been done yet.
+	StateConnected State = iota
+	// StateReady means the handshake is done and the protocol version is negotiated.
+	StateReady
+	// StateReconnecting means the connection was lost and the client is redialing.
+	StateReconnecting
+	// StateShutdown means the client has shut down, it is the final state.
+	StateShutdown
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReady:
+		return "ready"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateShutdown:
+		return "shutdown"
+	}
+
+	return "unknown"
+}
+
+// StateEvent describes a transition of the connection state.
+type StateEvent struct {
+	// State is the new state.
+	State State
+	// Previous is the state before the transition.
+	Previous State
+	// Time is the time of the transition.
+	Time time.Time
+	// Since is the time the previous state was entered.
+	Since time.Time
+	// Addr is the address of the remote server if the transport reports it.
+	Addr string
+	// Protocol is the negotiated protocol version, it is empty until the handshake
+	// on the current connection is done.
+	Protocol string
+	// Err is the error which caused the transition, e.g. the transport error
+	// before reconnecting or the cause of the shutdown.
+	Err error
+}
+
+// addresser is implemented by transports which know the address of the remote server.
+type addresser interface {
+	Addr() string
+}
+
+// State returns the current connection state.
+func (s *Client) State() State {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	return s.state.State
+}
+
+// SubscribeState returns a channel receiving the state transitions of the client
+// until ctx is done. The first event reports the current state. The channel is
+// closed after the transition to StateShutdown. Events are queued, a slow reader
+// does not block the client.
+func (s *Client) SubscribeState(ctx context.Context) <-chan StateEvent {
+	l := &stateListener{
+		out:  make(chan StateEvent),
+		wake: make(chan struct{}, 1),
+	}
+
+	s.stateLock.Lock()
+	current := s.state
+	current.Previous = current.State
+	l.push(current, current.State == StateShutdown)
+
+	if current.State != StateShutdown {
+		s.stateListeners = append(s.stateListeners, l)
+	}
+	s.stateLock.Unlock()
+
+	go func() {
+		l.run(ctx)
+
+		s.stateLock.Lock()
+		s.stateListeners = slices.DeleteFunc(s.stateListeners, func(val *stateListener) bool {
+			return val == l
+		})
+		s.stateLock.Unlock()
+	}()
+
+	return l.out
+}
+
+// setState changes the state and notifies the listeners. A new connection starts
+// without a negotiated protocol, the other transitions keep the address and the
+// protocol of the current connection unless protocol is set.
+func (s *Client) setState(state State, protocol string, err error) {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	prev := s.state
+	if prev.State == state || prev.State == StateShutdown {
+		return
+	}
+
+	event := StateEvent{
+		State:    state,
+		Previous: prev.State,
+		Time:     time.Now(),
+		Since:    prev.Time,
+		Addr:     prev.Addr,
+		Protocol: prev.Protocol,
+		Err:      err,
+	}
+
+	if state == StateConnected {
+		event.Addr = s.remoteAddr()
+		event.Protocol = ""
+	}
+
+	if protocol != "" {
+		event.Protocol = protocol
+	}
+
+	s.state = event
+
+	for _, l := range s.stateListeners {
+		l.push(event, state == StateShutdown)
+	}
+
+	if state == StateShutdown {
+		s.stateListeners = nil
+	}
+}
+
+// remoteAddr returns the address of the current transport or an empty string.
+func (s *Client) remoteAddr() string {
+	addr, _ := Get(s.transport, func(val Transport) (string, bool) {
+		return transportAddr(val), true
+	})
+
+	return addr
+}
+
+// transportAddr returns the address of the remote server if transport reports it.
+func transportAddr(transport Transport) string {
+	if a, ok := transport.(addresser); ok {
+		return a.Addr()
+	}
+
+	return ""
+}
+
+// stateListener queues the state events of a subscriber.
+type stateListener struct {
+	out  chan StateEvent
+	wake chan struct{}
+
+	lock   sync.Mutex
+	queue  []StateEvent
+	closed bool
+}
+
+// push queues event, last closes the channel after the event has been delivered.
+func (l *stateListener) push(event StateEvent, last bool) {
+	l.lock.Lock()
+	l.queue = append(l.queue, event)
+	l.closed = l.closed || last
+	l.lock.Unlock()
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (l *stateListener) run(ctx context.Context) {
+	defer close(l.out)
+
+	for {
+		l.lock.Lock()
+		queue, closed := l.queue, l.closed
+		l.queue = nil
+		l.lock.Unlock()
+
+		for _, event := range queue {
+			select {
+			case l.out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if closed {
+			return
+		}
+
+		if len(queue) > 0 {
+			continue
+		}
+
+		select {
+		case <-l.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
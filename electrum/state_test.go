@@ -0,0 +1,112 @@
+package electrum_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+	"github.com/zauberhaus/go-electrum/electrum/electrumtest"
+)
+
+func nextState(t *testing.T, events <-chan electrum.StateEvent) electrum.StateEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "state channel closed")
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no state event")
+	}
+
+	return electrum.StateEvent{}
+}
+
+func TestClient_State(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client, err := electrum.NewReconnectingClient(ctx, server.Dial(),
+		electrum.WithBackoff(electrum.Backoff{Min: 10 * time.Millisecond}))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	assert.Equal(t, electrum.StateConnected, client.State())
+
+	events := client.SubscribeState(ctx)
+
+	event := nextState(t, events)
+	assert.Equal(t, electrum.StateConnected, event.State)
+	assert.Equal(t, "pipe", event.Addr)
+	assert.Empty(t, event.Protocol)
+
+	_, _, err = client.ServerVersion(ctx)
+	require.NoError(t, err)
+
+	event = nextState(t, events)
+	assert.Equal(t, electrum.StateReady, event.State)
+	assert.Equal(t, electrum.StateConnected, event.Previous)
+	assert.Equal(t, electrumtest.ProtocolMax, event.Protocol)
+	assert.False(t, event.Since.After(event.Time))
+	assert.Equal(t, electrum.StateReady, client.State())
+
+	server.Disconnect()
+
+	event = nextState(t, events)
+	assert.Equal(t, electrum.StateReconnecting, event.State)
+	assert.Equal(t, electrum.StateReady, event.Previous)
+	assert.Error(t, event.Err)
+	assert.Equal(t, electrumtest.ProtocolMax, event.Protocol)
+
+	event = nextState(t, events)
+	assert.Equal(t, electrum.StateConnected, event.State)
+	assert.Empty(t, event.Protocol)
+	assert.NoError(t, event.Err)
+
+	event = nextState(t, events)
+	assert.Equal(t, electrum.StateReady, event.State)
+	assert.Equal(t, electrumtest.ProtocolMax, event.Protocol)
+
+	client.Shutdown()
+
+	event = nextState(t, events)
+	assert.Equal(t, electrum.StateShutdown, event.State)
+	assert.ErrorIs(t, event.Err, electrum.ErrServerShutdown)
+
+	_, ok := <-events
+	assert.False(t, ok)
+
+	// a late subscriber gets the final state
+	events = client.SubscribeState(ctx)
+	assert.Equal(t, electrum.StateShutdown, nextState(t, events).State)
+
+	_, ok = <-events
+	assert.False(t, ok)
+}
+
+func TestClient_SubscribeStateCancel(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport)
+	defer client.Shutdown()
+
+	sub, cancel := context.WithCancel(ctx)
+	events := client.SubscribeState(sub)
+	assert.Equal(t, electrum.StateConnected, nextState(t, events).State)
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "channel not closed")
+	}
+}
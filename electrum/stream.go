@@ -0,0 +1,347 @@
+package electrum
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zauberhaus/logger"
+)
+
+// commandGracePeriod is the time a command gets to exit after its stdin has been closed.
+const commandGracePeriod = time.Second
+
+// StreamTransport store information about a transport exchanging newline terminated
+// messages over any byte stream, e.g. a net.Conn, a Unix socket or the stdin and
+// stdout of a subprocess.
+type StreamTransport struct {
+	rwc       io.ReadWriteCloser
+	name      string
+	responses chan []byte
+	errors    chan error
+
+	readTimeout    time.Duration
+	maxMessageSize int
+	writer         *writeQueue
+
+	observer       TransportObserver
+	disconnectOnce sync.Once
+
+	failure  *Atomic[error]
+	failOnce sync.Once
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	log logger.Logger
+}
+
+// NewStreamTransport initialize a new transport on an already opened stream. The
+// read timeout is only applied if rwc has a SetReadDeadline method like net.Conn.
+func NewStreamTransport(ctx context.Context, rwc io.ReadWriteCloser, opts ...TransportOption) *StreamTransport {
+	name := "stream"
+	if conn, ok := rwc.(interface{ RemoteAddr() net.Addr }); ok && conn.RemoteAddr() != nil {
+		name = conn.RemoteAddr().String()
+	}
+
+	return newStreamTransport(rwc, name, newTransportConfig(opts), logger.GetLogger(ctx))
+}
+
+func newStreamTransport(rwc io.ReadWriteCloser, name string, cfg transportConfig, log logger.Logger) *StreamTransport {
+	t := &StreamTransport{
+		rwc:            rwc,
+		name:           name,
+		responses:      make(chan []byte),
+		errors:         make(chan error),
+		readTimeout:    cfg.readTimeout,
+		maxMessageSize: cfg.maxMessageSize,
+		observer:       cfg.observer,
+		failure:        MakeAtomic[error](nil),
+		done:           make(chan struct{}),
+		log:            log,
+	}
+
+	t.observer.Connected(name)
+
+	t.writer = newWriteQueue(cfg.writeQueueSize, cfg.writeTimeout, t.done, t.flush, t.fail)
+
+	go t.listen()
+
+	return t
+}
+
+// NewUnixTransport opens a new connection to a Unix socket. addr is a path or a
+// unix:// url.
+func NewUnixTransport(ctx context.Context, addr string, opts ...TransportOption) (*StreamTransport, error) {
+	log := logger.GetLogger(ctx)
+	cfg := newTransportConfig(opts)
+
+	path := strings.TrimPrefix(addr, "unix://")
+
+	conn, err := cfg.dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStreamTransport(conn, "unix://"+path, cfg, log), nil
+}
+
+// NewCommandTransport starts cmd and talks to it through its stdin and stdout.
+// Closing the transport closes stdin and kills the command if it does not exit
+// within a second.
+func NewCommandTransport(ctx context.Context, cmd *exec.Cmd, opts ...TransportOption) (*StreamTransport, error) {
+	log := logger.GetLogger(ctx)
+	cfg := newTransportConfig(opts)
+
+	// Own pipes instead of cmd.StdoutPipe, Wait must not close stdout before
+	// everything has been read.
+	stdinR, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close()
+		stdin.Close()
+		return nil, err
+	}
+
+	cmd.Stdin = stdinR
+	cmd.Stdout = stdoutW
+
+	err = cmd.Start()
+
+	stdinR.Close()
+	stdoutW.Close()
+
+	if err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, err
+	}
+
+	conn := &commandConn{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		exited: make(chan struct{}),
+	}
+
+	go func() {
+		cmd.Wait()
+		close(conn.exited)
+	}()
+
+	return newStreamTransport(conn, cmd.Path, cfg, log), nil
+}
+
+// NewClientUnix initialize a new client for remote server and connects to the remote
+// server using a Unix socket.
+func NewClientUnix(ctx context.Context, addr string, opts ...ClientOption) (*Client, error) {
+	transport, err := NewUnixTransport(ctx, addr, newClientConfig(opts).transportOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(ctx, transport, opts...), nil
+}
+
+// NewClientCommand initialize a new client for a server running as subprocess and
+// talking JSON-RPC over stdin and stdout.
+func NewClientCommand(ctx context.Context, cmd *exec.Cmd, opts ...ClientOption) (*Client, error) {
+	transport, err := NewCommandTransport(ctx, cmd, newClientConfig(opts).transportOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(ctx, transport, opts...), nil
+}
+
+// DialUnix returns a DialFunc connecting to a Unix socket.
+func DialUnix(addr string, opts ...TransportOption) DialFunc {
+	return func(ctx context.Context) (Transport, error) {
+		return NewUnixTransport(ctx, addr, opts...)
+	}
+}
+
+// DialCommand returns a DialFunc starting a new instance of the command name for
+// every connection.
+func DialCommand(name string, args []string, opts ...TransportOption) DialFunc {
+	return func(ctx context.Context) (Transport, error) {
+		return NewCommandTransport(ctx, exec.Command(name, args...), opts...)
+	}
+}
+
+func (t *StreamTransport) listen() {
+	defer t.rwc.Close()
+	reader := bufio.NewReader(t.rwc)
+
+	deadline, _ := t.rwc.(interface{ SetReadDeadline(time.Time) error })
+
+	for {
+		if t.readTimeout > 0 && deadline != nil {
+			deadline.SetReadDeadline(time.Now().Add(t.readTimeout))
+		}
+
+		line, err := readLine(reader, t.maxMessageSize)
+		if err != nil {
+			// report why the connection has been dropped instead of the read error
+			if failure, ok := Get(t.failure, func(val error) (error, bool) {
+				return val, val != nil
+			}); ok {
+				err = failure
+			}
+
+			// an oversized message has been skipped, the connection is still usable
+			var tooLarge *MessageTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.disconnected(err)
+			}
+
+			select {
+			case t.errors <- err:
+			case <-t.done:
+				return
+			}
+
+			if tooLarge != nil {
+				continue
+			}
+
+			return
+		}
+		t.log.Debugf("%s -> %s", t.name, line)
+		t.observer.MessageReceived(len(line))
+
+		select {
+		case t.responses <- line:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// SendMessage sends a message to the remote server through the transport.
+func (t *StreamTransport) SendMessage(body []byte) error {
+	return t.SendMessageContext(context.Background(), body)
+}
+
+// SendMessageContext queues a message for the remote server and waits until it has
+// been written. Messages waiting in the queue are merged into a single write.
+func (t *StreamTransport) SendMessageContext(ctx context.Context, body []byte) error {
+	t.log.Debugf("%s <- %s", t.name, body)
+
+	if err := t.writer.send(ctx, body); err != nil {
+		return err
+	}
+
+	t.observer.MessageSent(len(body))
+
+	return nil
+}
+
+// flush writes bodies to the stream. The deadline is only applied if rwc has a
+// SetWriteDeadline method like net.Conn.
+func (t *StreamTransport) flush(deadline time.Time, bodies [][]byte) (int, error) {
+	if conn, ok := t.rwc.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	return writeConcat(t.rwc.Write, bodies)
+}
+
+// fail drops the connection after a failed or interrupted write, the listener
+// reports err.
+func (t *StreamTransport) fail(err error) {
+	t.failOnce.Do(func() {
+		t.failure.Change(func(error) (error, error) {
+			return err, nil
+		})
+
+		t.rwc.Close()
+	})
+}
+
+// Addr returns the address of the remote server or the name of the stream.
+func (t *StreamTransport) Addr() string {
+	return t.name
+}
+
+// Responses returns chan to transport responses.
+func (t *StreamTransport) Responses() <-chan []byte {
+	return t.responses
+}
+
+// Errors returns chan to transport errors.
+func (t *StreamTransport) Errors() <-chan error {
+	return t.errors
+}
+
+// Close closes the stream.
+func (t *StreamTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+
+	t.disconnected(nil)
+
+	return t.rwc.Close()
+}
+
+// disconnected reports the end of the connection once.
+func (t *StreamTransport) disconnected(err error) {
+	t.disconnectOnce.Do(func() {
+		t.observer.Disconnected(err)
+	})
+}
+
+// commandConn is the stream to a subprocess.
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  *os.File
+	stdout *os.File
+
+	exited    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *commandConn) Read(b []byte) (int, error) {
+	return c.stdout.Read(b)
+}
+
+func (c *commandConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+func (c *commandConn) SetReadDeadline(t time.Time) error {
+	return c.stdout.SetReadDeadline(t)
+}
+
+func (c *commandConn) SetWriteDeadline(t time.Time) error {
+	return c.stdin.SetWriteDeadline(t)
+}
+
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+
+		select {
+		case <-c.exited:
+		case <-time.After(commandGracePeriod):
+			c.cmd.Process.Kill()
+			<-c.exited
+		}
+
+		c.stdout.Close()
+	})
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package electrum_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestStreamTransport_Pipe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, server := net.Pipe()
+	go serveLines(server, func(method string, params json.RawMessage) any {
+		return method
+	})
+
+	c := electrum.NewClient(ctx, electrum.NewStreamTransport(ctx, client))
+	defer c.Shutdown()
+
+	banner, err := c.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "server.banner", banner)
+}
+
+func TestStreamTransport_Unix(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "electrs.sock")
+
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveLines(conn, func(method string, params json.RawMessage) any {
+				return method
+			})
+		}
+	}()
+
+	client, err := electrum.NewClientUnix(ctx, "unix://"+path)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "server.banner", banner)
+}
+
+func TestStreamTransport_Command(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStreamHelperProcess$")
+	// the race detector sleeps a second before exiting by default
+	cmd.Env = append(os.Environ(), "ELECTRUM_HELPER_PROCESS=1", "GORACE=atexit_sleep_ms=0")
+
+	client, err := electrum.NewClientCommand(ctx, cmd)
+	require.NoError(t, err)
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "server.banner", banner)
+
+	// closing stdin ends the helper, Shutdown waits for it
+	client.Shutdown()
+
+	require.NotNil(t, cmd.ProcessState)
+	assert.True(t, cmd.ProcessState.Success())
+}
+
+// TestStreamHelperProcess is a line based JSON-RPC server on stdin and stdout used
+// by TestStreamTransport_Command.
+func TestStreamHelperProcess(t *testing.T) {
+	if os.Getenv("ELECTRUM_HELPER_PROCESS") != "1" {
+		t.Skip("helper process")
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			t.Fatal(err)
+		}
+
+		if req.Method == "server.version" {
+			fmt.Printf(`{"jsonrpc":"2.0","id":%d,"result":["Fake 1.0",%q]}`+"\n", req.ID, electrum.ProtocolMax)
+			continue
+		}
+
+		fmt.Printf(`{"jsonrpc":"2.0","id":%d,"result":%q}`+"\n", req.ID, req.Method)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"slices"
 	"sync"
 
 	"github.com/zauberhaus/logger"
@@ -32,16 +33,32 @@ func (s *Client) SubscribeHeaders(ctx context.Context) (<-chan *SubscribeHeaders
 
 	var resp SubscribeHeadersResp
 
+	// Listen before subscribing, the first notification may directly follow the response.
+	ch := s.listenPush("blockchain.headers.subscribe")
+
 	err := s.request(ctx, "blockchain.headers.subscribe", []interface{}{}, &resp)
 	if err != nil {
+		s.unlistenPush("blockchain.headers.subscribe", ch)
 		return nil, err
 	}
 
 	respChan := make(chan *SubscribeHeadersResult, 100)
 	respChan <- resp.Result
 
+	s.addSubscription(func(ctx context.Context) error {
+		var resp SubscribeHeadersResp
+
+		err := s.request(ctx, "blockchain.headers.subscribe", []interface{}{}, &resp)
+		if err != nil {
+			return err
+		}
+
+		offer(respChan, resp.Result)
+
+		return nil
+	})
+
 	go func() {
-		ch := s.listenPush("blockchain.headers.subscribe")
 		for {
 			select {
 			case <-s.quit:
@@ -60,7 +77,11 @@ func (s *Client) SubscribeHeaders(ctx context.Context) (<-chan *SubscribeHeaders
 				}
 
 				for _, param := range resp.Params {
-					respChan <- param
+					select {
+					case respChan <- param:
+					case <-s.quit:
+						return
+					}
 				}
 			}
 		}
@@ -102,8 +123,11 @@ func (s *Client) SubscribeScripthash() (*ScripthashSubscription, <-chan *Subscri
 		scripthashMap: make(map[string]string),
 	}
 
+	s.addSubscription(sub.Resubscribe)
+
+	ch := s.listenPush("blockchain.scripthash.subscribe")
+
 	go func() {
-		ch := s.listenPush("blockchain.scripthash.subscribe")
 		for {
 			select {
 			case <-s.quit:
@@ -134,7 +158,11 @@ func (s *Client) SubscribeScripthash() (*ScripthashSubscription, <-chan *Subscri
 				sub.lock.RUnlock()
 
 				if found {
-					sub.notifChan <- &resp
+					select {
+					case sub.notifChan <- &resp:
+					case <-s.quit:
+						return
+					}
 				}
 			}
 		}
@@ -152,17 +180,20 @@ func (sub *ScripthashSubscription) Add(ctx context.Context, scripthash string, a
 		return err
 	}
 
-	if len(resp.Result) > 0 {
-		sub.notifChan <- &SubscribeNotif{[2]string{scripthash, resp.Result}}
-	}
-
 	sub.lock.Lock()
-	sub.subscribedSH = append(sub.subscribedSH[:], scripthash)
+	if !slices.Contains(sub.subscribedSH, scripthash) {
+		sub.subscribedSH = append(sub.subscribedSH, scripthash)
+	}
 	if len(address) > 0 {
 		sub.scripthashMap[scripthash] = address[0]
 	}
 	sub.lock.Unlock()
 
+	if len(resp.Result) > 0 {
+		// don't block a restore if the consumer isn't draining the channel
+		offer(sub.notifChan, &SubscribeNotif{[2]string{scripthash, resp.Result}})
+	}
+
 	return nil
 }
 
@@ -183,9 +214,8 @@ func (sub *ScripthashSubscription) Remove(ctx context.Context, scripthash string
 		return errors.New("scripthash not found")
 	}
 
-	var resp BasicResp
-
-	err := sub.server.request(ctx, "blockchain.scripthash.unsubscribe", []interface{}{scripthash}, &resp)
+	// the result is a bool, not a string
+	err := sub.server.request(ctx, "blockchain.scripthash.unsubscribe", []interface{}{scripthash}, nil)
 	if err != nil {
 		return err
 	}
@@ -253,21 +283,23 @@ func (sub *ScripthashSubscription) RemoveAddress(address string) error {
 	return errors.New("scripthash not found")
 }
 
-// Resubscribe ...
+// Resubscribe subscribes all scripthashes of this subscription again. A reconnecting
+// client calls it automatically after the connection has been restored. A failing
+// scripthash does not stop the others.
 func (sub *ScripthashSubscription) Resubscribe(ctx context.Context) error {
 	sub.lock.RLock()
 	snapshot := make([]string, len(sub.subscribedSH))
 	copy(snapshot, sub.subscribedSH)
 	sub.lock.RUnlock()
 
+	var errs []error
 	for _, v := range snapshot {
-		err := sub.Add(ctx, v)
-		if err != nil {
-			return err
+		if err := sub.Add(ctx, v); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // SubscribeMasternode subscribes to receive notifications when a masternode status changes.
@@ -275,8 +307,11 @@ func (sub *ScripthashSubscription) Resubscribe(ctx context.Context) error {
 func (s *Client) SubscribeMasternode(ctx context.Context, collateral string) (<-chan string, error) {
 	var resp BasicResp
 
+	ch := s.listenPush("blockchain.masternode.subscribe")
+
 	err := s.request(ctx, "blockchain.masternode.subscribe", []interface{}{collateral}, &resp)
 	if err != nil {
+		s.unlistenPush("blockchain.masternode.subscribe", ch)
 		return nil, err
 	}
 
@@ -285,8 +320,22 @@ func (s *Client) SubscribeMasternode(ctx context.Context, collateral string) (<-
 		respChan <- resp.Result
 	}
 
+	s.addSubscription(func(ctx context.Context) error {
+		var resp BasicResp
+
+		err := s.request(ctx, "blockchain.masternode.subscribe", []interface{}{collateral}, &resp)
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Result) > 0 {
+			offer(respChan, resp.Result)
+		}
+
+		return nil
+	})
+
 	go func() {
-		ch := s.listenPush("blockchain.masternode.subscribe")
 		for {
 			select {
 			case <-s.quit:
@@ -304,7 +353,11 @@ func (s *Client) SubscribeMasternode(ctx context.Context, collateral string) (<-
 				}
 
 				for _, param := range resp.Params {
-					respChan <- param
+					select {
+					case respChan <- param:
+					case <-s.quit:
+						return
+					}
 				}
 			}
 		}
@@ -312,3 +365,20 @@ func (s *Client) SubscribeMasternode(ctx context.Context, collateral string) (<-
 
 	return respChan, nil
 }
+
+// offer sends v to ch without blocking. If ch is full the oldest value is dropped,
+// so a consumer which is not draining the channel gets the latest state later.
+func offer[T any](ch chan T, v T) {
+	for {
+		select {
+		case ch <- v:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
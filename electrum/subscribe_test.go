@@ -3,6 +3,7 @@ package electrum_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -155,6 +156,29 @@ func TestScripthashSubscription(t *testing.T) {
 	assert.Equal(t, [2]string{scripthash, "resubscribed"}, notif.Params)
 }
 
+func TestScripthashSubscription_RemoveBoolResult(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, transport := newTestClient(ctx, t)
+	defer client.Shutdown()
+
+	sub, notifChan := client.SubscribeScripthash()
+
+	err := transport.exec(1, "status", func() error {
+		return sub.Add(ctx, "scripthash")
+	})
+	require.NoError(t, err)
+	<-notifChan
+
+	// the server answers the unsubscribe with a bool, not a string
+	err = transport.exec(2, true, func() error {
+		return sub.Remove(ctx, "scripthash")
+	})
+	require.NoError(t, err)
+	assert.Empty(t, sub.SH())
+}
+
 func TestSubscribeMasternode(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -197,3 +221,39 @@ func TestSubscribeMasternode(t *testing.T) {
 	status = <-statusChan
 	assert.Equal(t, "new_status", status)
 }
+
+func TestScripthashSubscription_AddDoesNotBlock(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake())
+	defer client.Shutdown()
+
+	stop := startAutoResponder(transport, "status")
+	defer stop()
+
+	sub, notifChan := client.SubscribeScripthash()
+
+	// nobody drains the notifications, fill the channel
+	for i := range 10 {
+		require.NoError(t, sub.Add(ctx, fmt.Sprintf("sh%d", i)))
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sub.Add(ctx, "full")
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "add blocked on a full channel")
+	}
+
+	assert.Contains(t, sub.SH(), "full")
+
+	// the oldest status made room for the newest one
+	assert.Len(t, notifChan, 10)
+	assert.Equal(t, [2]string{"sh1", "status"}, (<-notifChan).Params)
+}
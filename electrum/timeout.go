@@ -0,0 +1,84 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultTimeout limits the time a request waits for its response if the context
+// of the call has no deadline.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultMethodTimeouts are the default timeouts of methods whose responses may
+// take longer than DefaultTimeout, e.g. the history of busy addresses.
+var DefaultMethodTimeouts = map[string]time.Duration{
+	"blockchain.scripthash.get_history": 2 * time.Minute,
+	"blockchain.scripthash.get_mempool": 2 * time.Minute,
+	"blockchain.scripthash.listunspent": 2 * time.Minute,
+	"blockchain.transaction.broadcast":  2 * time.Minute,
+}
+
+// WithTimeout sets the timeout of requests whose context has no deadline. Zero
+// disables the timeout, requests wait until the context is cancelled.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = d
+	}
+}
+
+// WithMethodTimeout sets the timeout of method, replacing the client timeout for
+// this method. Zero disables the timeout of method.
+func WithMethodTimeout(method string, d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.methodTimeouts[method] = d
+	}
+}
+
+// methodTimeout returns the timeout of method.
+func (c *clientConfig) methodTimeout(method string) time.Duration {
+	if d, ok := c.methodTimeouts[method]; ok {
+		return d
+	}
+
+	return c.timeout
+}
+
+// batchTimeout returns the longest timeout of the methods in requests.
+func (c *clientConfig) batchTimeout(requests []BatchRequest) time.Duration {
+	var timeout time.Duration
+
+	for _, request := range requests {
+		d := c.methodTimeout(request.Method)
+		if d <= 0 {
+			return 0
+		}
+
+		timeout = max(timeout, d)
+	}
+
+	return timeout
+}
+
+// withTimeout applies the timeout of method to ctx unless ctx has a deadline. When
+// the timeout expires, the cause of ctx is an error matching ErrTimeout.
+func withTimeout(ctx context.Context, method string, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || d <= 0 {
+		return ctx, func() {}
+	}
+
+	err := fmt.Errorf("%w: %s after %v: %w", ErrTimeout, method, d, context.DeadlineExceeded)
+
+	return context.WithTimeoutCause(ctx, d, err)
+}
+
+// contextErr returns the error of a done ctx. Expired timeouts of the client are
+// reported as ErrTimeout.
+func contextErr(ctx context.Context) error {
+	if cause := context.Cause(ctx); errors.Is(cause, ErrTimeout) {
+		return cause
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,144 @@
+package electrum_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+func TestTimeout_Default(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake(), electrum.WithTimeout(20*time.Millisecond))
+	defer client.Shutdown()
+
+	_, err := client.ServerBanner(ctx)
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Equal(t, 0, client.Stats().Pending)
+	assert.False(t, client.IsShutdown())
+}
+
+func TestTimeout_Method(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport,
+		electrum.WithoutHandshake(),
+		electrum.WithTimeout(0),
+		electrum.WithMethodTimeout("server.banner", 20*time.Millisecond),
+	)
+	defer client.Shutdown()
+
+	_, err := client.ServerBanner(ctx)
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+
+	// the deadline of the caller wins
+	deadline, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	_, err = client.ServerDonation(deadline)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, electrum.ErrTimeout)
+}
+
+func TestTimeout_Batch(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake(), electrum.WithTimeout(20*time.Millisecond))
+	defer client.Shutdown()
+
+	batch := client.NewBatch()
+	call := batch.GetBalance("00")
+
+	err := batch.Send(ctx)
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+
+	_, err = call.Result()
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+}
+
+func TestTimeout_Unresponsive(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport,
+		electrum.WithoutHandshake(),
+		electrum.WithTimeout(10*time.Millisecond),
+		electrum.WithKeepAlive(time.Hour, 2),
+	)
+
+	_, err := client.ServerBanner(ctx)
+	require.ErrorIs(t, err, electrum.ErrTimeout)
+	assert.False(t, client.IsShutdown())
+
+	_, err = client.ServerBanner(ctx)
+	require.ErrorIs(t, err, electrum.ErrTimeout)
+
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out requests did not drop the connection")
+	}
+
+	assert.ErrorIs(t, client.Err(), electrum.ErrUnresponsive)
+	assert.ErrorIs(t, client.Err(), electrum.ErrTimeout)
+}
+
+func TestTimeout_UnresponsiveWithoutKeepAlive(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake(), electrum.WithTimeout(10*time.Millisecond))
+
+	for range electrum.DefaultKeepAliveFailures - 1 {
+		_, err := client.ServerBanner(ctx)
+		require.ErrorIs(t, err, electrum.ErrTimeout)
+		assert.False(t, client.IsShutdown())
+	}
+
+	_, err := client.ServerBanner(ctx)
+	require.ErrorIs(t, err, electrum.ErrTimeout)
+
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out requests did not drop the connection")
+	}
+
+	assert.ErrorIs(t, client.Err(), electrum.ErrUnresponsive)
+}
+
+func TestTimeout_ReconnectWithoutKeepAlive(t *testing.T) {
+	ctx := context.Background()
+
+	first := NewMockTransport()
+	second := NewMockTransport()
+
+	client := electrum.NewClient(ctx, first,
+		electrum.WithoutHandshake(),
+		electrum.WithTimeout(10*time.Millisecond),
+		electrum.WithReconnect(dialSequence(second)),
+		electrum.WithBackoff(testBackoff),
+	)
+	defer client.Shutdown()
+
+	for range electrum.DefaultKeepAliveFailures {
+		_, err := client.ServerBanner(ctx)
+		require.ErrorIs(t, err, electrum.ErrTimeout)
+	}
+
+	method := respond(t, second, func(method string) any {
+		return [2]string{"ElectrumX 1.16.0", "1.4"}
+	})
+
+	assert.Equal(t, "server.version", method)
+	assert.False(t, client.IsShutdown())
+}
@@ -0,0 +1,349 @@
+package electrum
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PinType selects the part of the certificate which gets pinned.
+type PinType string
+
+const (
+	// PinCertificate pins the SHA-256 hash of the complete leaf certificate.
+	PinCertificate PinType = "cert"
+
+	// PinSPKI pins the SHA-256 hash of the public key of the leaf certificate,
+	// so a server can renew its certificate while keeping the key.
+	PinSPKI PinType = "spki"
+)
+
+var (
+	// ErrCertificateChanged throws an error if a server presents a certificate that
+	// does not match the pinned one.
+	ErrCertificateChanged = errors.New("server certificate has changed")
+
+	// ErrInvalidPin throws an error if a pin can not be parsed.
+	ErrInvalidPin = errors.New("invalid certificate pin")
+)
+
+// Pin is the fingerprint of a trusted server certificate.
+type Pin struct {
+	Type PinType
+	Hash string // hex encoded SHA-256
+}
+
+// NewPin returns the pin of cert.
+func NewPin(cert *x509.Certificate, typ PinType) Pin {
+	var sum [32]byte
+
+	if typ == PinSPKI {
+		sum = sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	} else {
+		typ = PinCertificate
+		sum = sha256.Sum256(cert.Raw)
+	}
+
+	return Pin{
+		Type: typ,
+		Hash: hex.EncodeToString(sum[:]),
+	}
+}
+
+// ParsePin parses a pin in the form "type:hash".
+func ParsePin(s string) (Pin, error) {
+	typ, hash, ok := strings.Cut(s, ":")
+	if !ok || (PinType(typ) != PinCertificate && PinType(typ) != PinSPKI) {
+		return Pin{}, fmt.Errorf("%w: %s", ErrInvalidPin, s)
+	}
+
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+		return Pin{}, fmt.Errorf("%w: %s", ErrInvalidPin, s)
+	}
+
+	return Pin{
+		Type: PinType(typ),
+		Hash: strings.ToLower(hash),
+	}, nil
+}
+
+// Matches reports whether cert matches the pin.
+func (p Pin) Matches(cert *x509.Certificate) bool {
+	return NewPin(cert, p.Type) == p
+}
+
+func (p Pin) String() string {
+	return string(p.Type) + ":" + p.Hash
+}
+
+// CertificateChangedError is returned if a server presents a certificate that does not
+// match the pinned one. Use TrustStore.Save or RotatePin to accept the new certificate.
+type CertificateChangedError struct {
+	Addr      string
+	Pinned    Pin
+	Presented Pin
+}
+
+func (e *CertificateChangedError) Error() string {
+	return fmt.Sprintf("%s: certificate of %s has changed: pinned %s, presented %s",
+		ErrCertificateChanged, e.Addr, e.Pinned, e.Presented)
+}
+
+func (e *CertificateChangedError) Unwrap() error {
+	return ErrCertificateChanged
+}
+
+// TrustStore stores the pinned certificates keyed by host:port.
+type TrustStore interface {
+	// Lookup returns the pin of addr and if it exists.
+	Lookup(addr string) (Pin, bool, error)
+	// Save pins addr to pin, replacing an existing pin.
+	Save(addr string, pin Pin) error
+	// Remove forgets the pin of addr.
+	Remove(addr string) error
+}
+
+// MemoryTrustStore is a TrustStore keeping the pins in memory.
+type MemoryTrustStore struct {
+	pins map[string]Pin
+	lock sync.RWMutex
+}
+
+// NewMemoryTrustStore returns an empty in-memory trust store.
+func NewMemoryTrustStore() *MemoryTrustStore {
+	return &MemoryTrustStore{
+		pins: make(map[string]Pin),
+	}
+}
+
+// Lookup returns the pin of addr and if it exists.
+func (m *MemoryTrustStore) Lookup(addr string) (Pin, bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	pin, ok := m.pins[trustKey(addr)]
+	return pin, ok, nil
+}
+
+// Save pins addr to pin.
+func (m *MemoryTrustStore) Save(addr string, pin Pin) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.pins[trustKey(addr)] = pin
+	return nil
+}
+
+// Remove forgets the pin of addr.
+func (m *MemoryTrustStore) Remove(addr string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.pins, trustKey(addr))
+	return nil
+}
+
+// FileTrustStore is a TrustStore persisted in a file similar to the SSH known_hosts.
+// Every line holds an address and its pin, e.g.
+//
+//	electrum.example.com:50002 spki:5d2c...
+type FileTrustStore struct {
+	path string
+	mem  *MemoryTrustStore
+	lock sync.Mutex
+}
+
+// NewFileTrustStore loads the trust store at path. A missing file is treated as
+// an empty store and created on the first Save.
+func NewFileTrustStore(path string) (*FileTrustStore, error) {
+	store := &FileTrustStore{
+		path: path,
+		mem:  NewMemoryTrustStore(),
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: invalid line", path, n)
+		}
+
+		pin, err := ParsePin(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+
+		store.mem.pins[trustKey(fields[0])] = pin
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Lookup returns the pin of addr and if it exists.
+func (f *FileTrustStore) Lookup(addr string) (Pin, bool, error) {
+	return f.mem.Lookup(addr)
+}
+
+// Save pins addr to pin and writes the file.
+func (f *FileTrustStore) Save(addr string, pin Pin) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.mem.Save(addr, pin)
+	return f.write()
+}
+
+// Remove forgets the pin of addr and writes the file.
+func (f *FileTrustStore) Remove(addr string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.mem.Remove(addr)
+	return f.write()
+}
+
+func (f *FileTrustStore) write() error {
+	f.mem.lock.RLock()
+	lines := make([]string, 0, len(f.mem.pins))
+	for addr, pin := range f.mem.pins {
+		lines = append(lines, addr+" "+pin.String()+"\n")
+	}
+	f.mem.lock.RUnlock()
+
+	slices.Sort(lines)
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strings.Join(lines, "")); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func trustKey(addr string) string {
+	return strings.ToLower(addr)
+}
+
+// TOFUConfig returns a copy of config that trusts the certificate addr presents on
+// first use, pins it in store and rejects any other certificate afterwards with a
+// CertificateChangedError. The CA chain is not verified.
+func TOFUConfig(config *tls.Config, store TrustStore, addr string, typ PinType) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+
+	config = config.Clone()
+	config.InsecureSkipVerify = true
+
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			config.ServerName = host
+		}
+	}
+
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("server %s presented no certificate", addr)
+		}
+
+		leaf := cs.PeerCertificates[0]
+
+		pin, ok, err := store.Lookup(addr)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return store.Save(addr, NewPin(leaf, typ))
+		}
+
+		if !pin.Matches(leaf) {
+			return &CertificateChangedError{
+				Addr:      addr,
+				Pinned:    pin,
+				Presented: NewPin(leaf, pin.Type),
+			}
+		}
+
+		return nil
+	}
+
+	return config
+}
+
+// WithTrustStore makes SSL and secure WebSocket transports use trust on first use
+// certificate pinning with store. See TOFUConfig.
+func WithTrustStore(store TrustStore, typ PinType) TransportOption {
+	return func(c *transportConfig) {
+		c.trustStore = store
+		c.pinType = typ
+	}
+}
+
+// RotatePin connects to addr and replaces its pin in store with the certificate the
+// server presents now. It returns the new pin.
+func RotatePin(ctx context.Context, store TrustStore, addr string, typ PinType, opts ...TransportOption) (Pin, error) {
+	cfg := newTransportConfig(opts)
+
+	var pin Pin
+
+	config := TOFUConfig(nil, NewMemoryTrustStore(), addr, typ)
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("server %s presented no certificate", addr)
+		}
+
+		pin = NewPin(cs.PeerCertificates[0], typ)
+		return nil
+	}
+
+	conn, err := dialTLS(ctx, cfg.dialer, addr, config)
+	if err != nil {
+		return Pin{}, err
+	}
+	conn.Close()
+
+	if err := store.Save(addr, pin); err != nil {
+		return Pin{}, err
+	}
+
+	return pin, nil
+}
@@ -0,0 +1,173 @@
+package electrum_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// selfSignedCert creates a self-signed certificate for 127.0.0.1.
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "electrum test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTLSServer starts a line based JSON-RPC server using TLS. The certificate can be
+// replaced at any time through the returned pointer.
+func newTLSServer(t *testing.T, cert tls.Certificate) (string, *atomic.Pointer[tls.Certificate]) {
+	t.Helper()
+
+	current := &atomic.Pointer[tls.Certificate]{}
+	current.Store(&cert)
+
+	config := &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return current.Load(), nil
+		},
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveLines(conn, func(method string, params json.RawMessage) any {
+				return nil
+			})
+		}
+	}()
+
+	return l.Addr().String(), current
+}
+
+func TestTOFU(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := selfSignedCert(t)
+	addr, cert := newTLSServer(t, first)
+
+	store := electrum.NewMemoryTrustStore()
+	opt := electrum.WithTransportOptions(electrum.WithTrustStore(store, electrum.PinSPKI))
+
+	connect := func() error {
+		client, err := electrum.NewClientSSL(ctx, addr, nil, opt)
+		if err != nil {
+			return err
+		}
+		defer client.Shutdown()
+
+		return client.Ping(ctx)
+	}
+
+	// first use pins the certificate
+	require.NoError(t, connect())
+
+	pin, ok, err := store.Lookup(addr)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	leaf, err := x509.ParseCertificate(first.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, electrum.NewPin(leaf, electrum.PinSPKI), pin)
+
+	// same certificate is accepted
+	require.NoError(t, connect())
+
+	// a changed certificate is rejected
+	second := selfSignedCert(t)
+	cert.Store(&second)
+
+	err = connect()
+	require.ErrorIs(t, err, electrum.ErrCertificateChanged)
+
+	var changed *electrum.CertificateChangedError
+	require.True(t, errors.As(err, &changed))
+	assert.Equal(t, addr, changed.Addr)
+	assert.Equal(t, pin, changed.Pinned)
+	assert.NotEqual(t, pin, changed.Presented)
+
+	// an explicit rotation accepts the new certificate
+	rotated, err := electrum.RotatePin(ctx, store, addr, electrum.PinSPKI)
+	require.NoError(t, err)
+	assert.Equal(t, changed.Presented, rotated)
+
+	require.NoError(t, connect())
+}
+
+func TestFileTrustStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_servers")
+
+	store, err := electrum.NewFileTrustStore(path)
+	require.NoError(t, err)
+
+	pin, err := electrum.ParsePin("spki:" + strings.Repeat("ab", 32))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Save("Electrum.Example.com:50002", pin))
+	require.NoError(t, store.Save("other.example.com:50002", electrum.Pin{Type: electrum.PinCertificate, Hash: pin.Hash}))
+	require.NoError(t, store.Remove("other.example.com:50002"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "electrum.example.com:50002 "+pin.String()+"\n", string(data))
+
+	loaded, err := electrum.NewFileTrustStore(path)
+	require.NoError(t, err)
+
+	got, ok, err := loaded.Lookup("electrum.example.com:50002")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, pin, got)
+
+	_, ok, err = loaded.Lookup("other.example.com:50002")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestParsePin(t *testing.T) {
+	_, err := electrum.ParsePin("md5:abcd")
+	assert.ErrorIs(t, err, electrum.ErrInvalidPin)
+
+	_, err = electrum.ParsePin("spki:abcd")
+	assert.ErrorIs(t, err, electrum.ErrInvalidPin)
+}
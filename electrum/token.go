@@ -0,0 +1,91 @@
+package electrum
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TokenFilter selects the UTXOs returned by ListUnspent by their CashTokens.
+type TokenFilter string
+
+const (
+	// TokenFilterInclude returns all UTXOs, it is the default.
+	TokenFilterInclude TokenFilter = "include"
+	// TokenFilterExclude returns only the UTXOs without tokens, which are safe to
+	// spend as plain coins.
+	TokenFilterExclude TokenFilter = "exclude"
+	// TokenFilterOnly returns only the UTXOs carrying tokens.
+	TokenFilterOnly TokenFilter = "tokens_only"
+)
+
+// NFT capabilities of a CashToken.
+const (
+	NFTCapabilityNone    = "none"
+	NFTCapabilityMutable = "mutable"
+	NFTCapabilityMinting = "minting"
+)
+
+// TokenData represents the CashTokens carried by an output on Bitcoin Cash.
+type TokenData struct {
+	// Category is the id of the token category in hex.
+	Category string `json:"category"`
+	// Amount is the amount of fungible tokens.
+	Amount uint64 `json:"amount"`
+	// NFT is the non-fungible token or nil if there is none.
+	NFT *NFT `json:"nft,omitempty"`
+}
+
+// NFT represents the non-fungible token of an output.
+type NFT struct {
+	Capability string `json:"capability"`
+	Commitment string `json:"commitment"`
+}
+
+// UnmarshalJSON accepts the amount as number or as string, the servers send it
+// as string as it may exceed the precision of JSON numbers.
+func (t *TokenData) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Category string          `json:"category"`
+		Amount   json.RawMessage `json:"amount"`
+		NFT      *NFT            `json:"nft"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	t.Category = raw.Category
+	t.NFT = raw.NFT
+	t.Amount = 0
+
+	amount := strings.Trim(string(raw.Amount), `"`)
+	if amount == "" || amount == "null" {
+		return nil
+	}
+
+	var err error
+	t.Amount, err = strconv.ParseUint(amount, 10, 64)
+
+	return err
+}
+
+// HasTokens reports whether the UTXO carries fungible or non-fungible tokens.
+func (r *ListUnspentResult) HasTokens() bool {
+	return r.TokenData != nil
+}
+
+// filterTokens removes the UTXOs not matching filter.
+func filterTokens(utxos []*ListUnspentResult, filter TokenFilter) []*ListUnspentResult {
+	switch filter {
+	case TokenFilterExclude:
+		return slices.DeleteFunc(utxos, (*ListUnspentResult).HasTokens)
+	case TokenFilterOnly:
+		return slices.DeleteFunc(utxos, func(r *ListUnspentResult) bool {
+			return !r.HasTokens()
+		})
+	}
+
+	return utxos
+}
@@ -0,0 +1,130 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+const tokenUTXOs = `[
+	{"height": 1, "tx_pos": 0, "tx_hash": "plain", "value": 1000},
+	{"height": 2, "tx_pos": 1, "tx_hash": "fungible", "value": 800,
+		"token_data": {"amount": "18446744073709551615", "category": "aa"}},
+	{"height": 3, "tx_pos": 2, "tx_hash": "nft", "value": 800,
+		"token_data": {"amount": "0", "category": "bb", "nft": {"capability": "minting", "commitment": "cc"}}}
+]`
+
+func TestTokenData_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want electrum.TokenData
+	}{
+		{"string", `{"category":"aa","amount":"100"}`, electrum.TokenData{Category: "aa", Amount: 100}},
+		{"number", `{"category":"aa","amount":100}`, electrum.TokenData{Category: "aa", Amount: 100}},
+		{"nft", `{"category":"bb","nft":{"capability":"mutable","commitment":"01"}}`, electrum.TokenData{
+			Category: "bb",
+			NFT:      &electrum.NFT{Capability: electrum.NFTCapabilityMutable, Commitment: "01"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data electrum.TokenData
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &data))
+			assert.Equal(t, tt.want, data)
+		})
+	}
+
+	var data electrum.TokenData
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"-1"}`), &data))
+}
+
+func TestTokenData_FieldNames(t *testing.T) {
+	want := &electrum.TokenData{Category: "aa", Amount: 5}
+
+	// listunspent uses the field name of the server
+	var utxo electrum.ListUnspentResult
+	require.NoError(t, json.Unmarshal([]byte(`{"tx_hash":"tx","token_data":{"category":"aa","amount":"5"}}`), &utxo))
+	assert.Equal(t, want, utxo.TokenData)
+
+	// verbose transactions pass the output of the node through
+	var vout electrum.Vout
+	require.NoError(t, json.Unmarshal([]byte(`{"n":0,"tokenData":{"category":"aa","amount":"5"}}`), &vout))
+	assert.Equal(t, want, vout.TokenData)
+
+	// the history has no tokens
+	var history electrum.GetMempoolResult
+	require.NoError(t, json.Unmarshal([]byte(`{"tx_hash":"tx","height":0,"fee":250}`), &history))
+	assert.Equal(t, electrum.GetMempoolResult{Hash: "tx", Fee: 250}, history)
+}
+
+func TestListUnspent_TokenFilter(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport)
+	defer client.Shutdown()
+
+	stop := startAutoResponder(transport, json.RawMessage(tokenUTXOs))
+	defer stop()
+
+	hashes := func(utxos []*electrum.ListUnspentResult) []string {
+		var result []string
+		for _, utxo := range utxos {
+			result = append(result, utxo.Hash)
+		}
+
+		return result
+	}
+
+	utxos, err := client.ListUnspent(ctx, "sh")
+	require.NoError(t, err)
+	require.Len(t, utxos, 3)
+	assert.Nil(t, utxos[0].TokenData)
+	assert.Equal(t, &electrum.TokenData{Category: "aa", Amount: 18446744073709551615}, utxos[1].TokenData)
+	assert.Equal(t, &electrum.TokenData{
+		Category: "bb",
+		NFT:      &electrum.NFT{Capability: electrum.NFTCapabilityMinting, Commitment: "cc"},
+	}, utxos[2].TokenData)
+
+	utxos, err = client.ListUnspent(ctx, "sh", electrum.TokenFilterInclude)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plain", "fungible", "nft"}, hashes(utxos))
+
+	utxos, err = client.ListUnspent(ctx, "sh", electrum.TokenFilterExclude)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plain"}, hashes(utxos))
+
+	utxos, err = client.ListUnspent(ctx, "sh", electrum.TokenFilterOnly)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fungible", "nft"}, hashes(utxos))
+}
+
+func TestGetTransaction_TokenData(t *testing.T) {
+	ctx := context.Background()
+
+	transport := NewMockTransport()
+	client := electrum.NewClient(ctx, transport)
+	defer client.Shutdown()
+
+	stop := startAutoResponder(transport, json.RawMessage(`{
+		"txid": "tx",
+		"vout": [
+			{"n": 0, "value": 0.00001, "scriptPubKey": {"type": "pubkeyhash"},
+				"tokenData": {"category": "aa", "amount": "5"}},
+			{"n": 1, "value": 0.5, "scriptPubKey": {"type": "pubkeyhash"}}
+		]
+	}`))
+	defer stop()
+
+	tx, err := client.GetTransaction(ctx, "tx")
+	require.NoError(t, err)
+	require.Len(t, tx.Vout, 2)
+	assert.Equal(t, &electrum.TokenData{Category: "aa", Amount: 5}, tx.Vout[0].TokenData)
+	assert.Nil(t, tx.Vout[1].TokenData)
+}
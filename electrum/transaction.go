@@ -56,6 +56,7 @@ type Vout struct {
 	N            uint32       `json:"n"`
 	ScriptPubkey ScriptPubkey `json:"scriptpubkey"`
 	Value        float64      `json:"value"`
+	TokenData    *TokenData   `json:"tokenData,omitempty"` // CashTokens on Bitcoin Cash.
 }
 
 // ScriptPubkey represents the script of that transaction output.
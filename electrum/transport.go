@@ -1,7 +1,6 @@
 package electrum
 
 import (
-	"bufio"
 	"context"
 	"crypto/tls"
 	"net"
@@ -9,97 +8,75 @@ import (
 	"github.com/zauberhaus/logger"
 )
 
+// Dialer opens network connections. *net.Dialer and *SOCKS5Dialer implement it.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
 // TCPTransport store information about the TCP transport.
 type TCPTransport struct {
-	conn      net.Conn
-	responses chan []byte
-	errors    chan error
-
-	log logger.Logger
+	*StreamTransport
 }
 
 // NewTCPTransport opens a new TCP connection to the remote server.
-func NewTCPTransport(ctx context.Context, addr string) (*TCPTransport, error) {
+func NewTCPTransport(ctx context.Context, addr string, opts ...TransportOption) (*TCPTransport, error) {
 	log := logger.GetLogger(ctx)
+	cfg := newTransportConfig(opts)
 
-	var d net.Dialer
-
-	conn, err := d.DialContext(ctx, "tcp", addr)
+	conn, err := cfg.dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return nil, err
 	}
 
-	tcp := &TCPTransport{
-		conn:      conn,
-		responses: make(chan []byte),
-		errors:    make(chan error),
-		log:       log,
-	}
-
-	go tcp.listen()
-
-	return tcp, nil
+	return newTCPTransport(conn, cfg, log), nil
 }
 
 // NewSSLTransport opens a new SSL connection to the remote server.
-func NewSSLTransport(ctx context.Context, addr string, config *tls.Config) (*TCPTransport, error) {
+func NewSSLTransport(ctx context.Context, addr string, config *tls.Config, opts ...TransportOption) (*TCPTransport, error) {
 	log := logger.GetLogger(ctx)
+	cfg := newTransportConfig(opts)
 
-	dialer := tls.Dialer{
-		NetDialer: &net.Dialer{},
-		Config:    config,
-	}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	conn, err := dialTLS(ctx, cfg.dialer, addr, cfg.tlsConfig(addr, config))
 	if err != nil {
 		return nil, err
 	}
 
-	tcp := &TCPTransport{
-		conn:      conn,
-		responses: make(chan []byte),
-		errors:    make(chan error),
-		log:       log,
-	}
-
-	go tcp.listen()
+	return newTCPTransport(conn, cfg, log), nil
+}
 
-	return tcp, nil
+func newTCPTransport(conn net.Conn, cfg transportConfig, log logger.Logger) *TCPTransport {
+	return &TCPTransport{
+		StreamTransport: newStreamTransport(conn, conn.RemoteAddr().String(), cfg, log),
+	}
 }
 
-func (t *TCPTransport) listen() {
-	defer t.conn.Close()
-	reader := bufio.NewReader(t.conn)
+// dialTLS opens a connection through dialer and runs the TLS handshake on it.
+func dialTLS(ctx context.Context, dialer Dialer, addr string, config *tls.Config) (*tls.Conn, error) {
+	raw, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		config = &tls.Config{}
+	}
 
-	for {
-		line, err := reader.ReadBytes(nl)
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
 		if err != nil {
-			t.errors <- err
-			break
+			raw.Close()
+			return nil, err
 		}
-		t.log.Debugf("%s -> %s", t.conn.RemoteAddr(), line)
 
-		t.responses <- line
+		config = config.Clone()
+		config.ServerName = host
 	}
-}
-
-// SendMessage sends a message to the remote server through the TCP transport.
-func (t *TCPTransport) SendMessage(body []byte) error {
-	t.log.Debugf("%s <- %s", t.conn.RemoteAddr(), body)
 
-	_, err := t.conn.Write(body)
-	return err
-}
-
-// Responses returns chan to TCP transport responses.
-func (t *TCPTransport) Responses() <-chan []byte {
-	return t.responses
-}
-
-// Errors returns chan to TCP transport errors.
-func (t *TCPTransport) Errors() <-chan error {
-	return t.errors
-}
+	conn := tls.Client(raw, config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, err
+	}
 
-func (t *TCPTransport) Close() error {
-	return t.conn.Close()
+	return conn, nil
 }
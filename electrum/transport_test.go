@@ -0,0 +1,85 @@
+package electrum_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// newTCPServer starts a line based JSON-RPC server on a local port. Every request
+// is answered with the result returned by handler for the request method, the
+// server.version handshake with ProtocolMax unless handler returns a version pair.
+func newTCPServer(t *testing.T, handler func(method string, params json.RawMessage) any) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveLines(conn, handler)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveLines(conn net.Conn, handler func(method string, params json.RawMessage) any) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			return
+		}
+
+		result := handler(req.Method, req.Params)
+		if _, ok := result.([]string); req.Method == "server.version" && !ok {
+			result = []string{"Fake 1.0", electrum.ProtocolMax}
+		}
+
+		resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		if _, err := conn.Write(append(resp, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+func TestTCPTransport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		return method
+	})
+
+	client, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "server.banner", banner)
+}
@@ -0,0 +1,183 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProtocolMax is the newest protocol version the client speaks.
+const ProtocolMax = "1.6"
+
+// versionRange is the range of protocol versions a method is available in.
+type versionRange struct {
+	added   string
+	removed string
+}
+
+// methodVersions lists the methods which are not available in all protocol versions
+// between ProtocolVersion and ProtocolMax.
+var methodVersions = map[string]versionRange{
+	"blockchain.scripthash.unsubscribe":        {added: "1.4.2"},
+	"blockchain.relayfee":                      {removed: "1.6"},
+	"blockchain.transaction.broadcast_package": {added: "1.6"},
+	"mempool.get_info":                         {added: "1.6"},
+}
+
+// WithProtocolVersion sets the range of protocol versions offered in the
+// server.version handshake. The default range is ProtocolVersion to ProtocolMax.
+func WithProtocolVersion(minVersion, maxVersion string) ClientOption {
+	return func(c *clientConfig) {
+		c.protocolMin = minVersion
+		c.protocolMax = maxVersion
+	}
+}
+
+// WithoutHandshake stops the client from sending server.version as the first message
+// of a new connection. The server then uses its default protocol version until
+// ServerVersion is called. Reconnects repeat the handshake anyway.
+func WithoutHandshake() ClientOption {
+	return func(c *clientConfig) {
+		c.handshake = false
+	}
+}
+
+// CompareVersions compares two protocol versions like "1.4.2" and returns a negative
+// number, zero or a positive number if a is older, equal or newer than b.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			return x - y
+		}
+	}
+
+	return 0
+}
+
+// protocolInRange reports whether version is between minVersion and maxVersion.
+func protocolInRange(version, minVersion, maxVersion string) bool {
+	return CompareVersions(version, minVersion) >= 0 && CompareVersions(version, maxVersion) <= 0
+}
+
+// ProtocolVersion returns the protocol version negotiated on the current connection
+// or an empty string before the handshake.
+func (s *Client) ProtocolVersion() string {
+	negotiated, _ := Get(s.negotiated, func(val [2]string) (string, bool) {
+		return val[1], true
+	})
+
+	return negotiated
+}
+
+// checkVersion returns ErrNotImplemented or ErrDeprecated if method is not available
+// in the negotiated protocol version. Before the handshake all methods are allowed.
+func (s *Client) checkVersion(method string) error {
+	r, ok := methodVersions[method]
+	if !ok {
+		return nil
+	}
+
+	protocol := s.ProtocolVersion()
+	if protocol == "" {
+		return nil
+	}
+
+	if r.added != "" && CompareVersions(protocol, r.added) < 0 {
+		return fmt.Errorf("%w: %s requires protocol %s, negotiated %s", ErrNotImplemented, method, r.added, protocol)
+	}
+
+	if r.removed != "" && CompareVersions(protocol, r.removed) >= 0 {
+		return fmt.Errorf("%w: %s was removed in protocol %s, negotiated %s", ErrDeprecated, method, r.removed, protocol)
+	}
+
+	return nil
+}
+
+// protocolAtLeast reports whether the negotiated protocol version is version or newer.
+func (s *Client) protocolAtLeast(version string) bool {
+	protocol := s.ProtocolVersion()
+
+	return protocol != "" && CompareVersions(protocol, version) >= 0
+}
+
+// startHandshake makes the requests wait for a new handshake. It returns the
+// channel to close when the handshake is done.
+func (s *Client) startHandshake() chan struct{} {
+	done := make(chan struct{})
+
+	s.negotiated.Reset()
+	s.handshakeErr.Reset()
+	s.handshakeDone.Change(func(chan struct{}) (chan struct{}, error) {
+		return done, nil
+	})
+
+	return done
+}
+
+// handshake negotiates the protocol version on a new connection and closes done
+// if it is set. If it fails, the connection is dropped, so the client reconnects
+// or shuts down.
+func (s *Client) handshake(done chan struct{}) error {
+	generation := s.generation.Load()
+	_, _, err := s.negotiate(s.ctx, s.config.protocolMin, s.config.protocolMax)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrHandshakeFailed, err)
+		s.handshakeErr.Change(func(error) (error, error) {
+			return err, nil
+		})
+	}
+
+	if done != nil {
+		close(done)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	s.log.Errorf("Handshake failed: %v", err)
+
+	// a lost connection is already being replaced
+	if !errors.Is(err, ErrConnectionLost) && s.generation.Load() == generation {
+		s.drop(err)
+	}
+
+	return err
+}
+
+// waitHandshake blocks until the handshake on the current connection is done and
+// returns its error.
+func (s *Client) waitHandshake(ctx context.Context) error {
+	done, _ := Get(s.handshakeDone, func(val chan struct{}) (chan struct{}, bool) {
+		return val, val != nil
+	})
+
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		err, _ := Get(s.handshakeErr, func(val error) (error, bool) {
+			return val, val != nil
+		})
+
+		return err
+	case <-s.quit:
+		return s.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
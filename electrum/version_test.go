@@ -0,0 +1,277 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+	"github.com/zauberhaus/go-electrum/electrum/electrumtest"
+)
+
+// newVersionServer starts a server negotiating protocol and records the requests.
+func newVersionServer(t *testing.T, protocol string) (string, func() []string) {
+	var lock sync.Mutex
+	var requests []string
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		lock.Lock()
+		requests = append(requests, method+" "+string(params))
+		lock.Unlock()
+
+		switch method {
+		case "server.version":
+			return []string{"Fake 1.0", protocol}
+		case "mempool.get_info":
+			return map[string]float64{"mempoolminfee": 0.00002, "minrelaytxfee": 0.00001, "incrementalrelayfee": 0.00001}
+		case "blockchain.relayfee":
+			return 0.00003
+		case "blockchain.block.headers":
+			return map[string]any{"count": 2, "headers": []string{"aa", "bb"}, "max": 2016}
+		}
+
+		return method
+	})
+
+	return addr, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return append([]string(nil), requests...)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Zero(t, electrum.CompareVersions("1.4", "1.4.0"))
+	assert.Negative(t, electrum.CompareVersions("1.4", "1.4.2"))
+	assert.Positive(t, electrum.CompareVersions("1.10", "1.6"))
+	assert.Negative(t, electrum.CompareVersions("1.4.2", "1.6"))
+}
+
+func TestServerVersion_Negotiate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.4.2")
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	assert.Empty(t, client.ProtocolVersion())
+
+	serverVer, protocolVer, err := client.ServerVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Fake 1.0", serverVer)
+	assert.Equal(t, "1.4.2", protocolVer)
+	assert.Equal(t, "1.4.2", client.ProtocolVersion())
+
+	// the negotiated version is remembered
+	_, protocolVer, err = client.ServerVersion(ctx, "1.4", "1.5")
+	require.NoError(t, err)
+	assert.Equal(t, "1.4.2", protocolVer)
+
+	// a range without it can not be negotiated again on the connection
+	_, _, err = client.ServerVersion(ctx, "1.6")
+	assert.ErrorIs(t, err, electrum.ErrProtocolMismatch)
+
+	assert.Equal(t, []string{
+		`server.version ["` + electrum.ClientVersion + `",["1.4","1.6"]]`,
+	}, requests())
+}
+
+func TestServerVersion_Single(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.4")
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithProtocolVersion("1.4", "1.4"))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, _, err = client.ServerVersion(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`server.version ["` + electrum.ClientVersion + `","1.4"]`,
+	}, requests())
+}
+
+func TestServerVersion_Mismatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, _ := newVersionServer(t, "1.2")
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, _, err = client.ServerVersion(ctx)
+	assert.ErrorIs(t, err, electrum.ErrProtocolMismatch)
+	assert.Empty(t, client.ProtocolVersion())
+}
+
+func TestVersionGated_Old(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.4")
+
+	client, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, _, err = client.ServerVersion(ctx)
+	require.NoError(t, err)
+
+	_, err = client.GetMempoolInfo(ctx)
+	assert.ErrorIs(t, err, electrum.ErrNotImplemented)
+
+	fee, err := client.GetRelayFee(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.00003), fee)
+
+	sub, _ := client.SubscribeScripthash()
+	require.NoError(t, sub.Add(ctx, "00"))
+	assert.ErrorIs(t, sub.Remove(ctx, "00"), electrum.ErrNotImplemented)
+
+	assert.Len(t, requests(), 3)
+}
+
+func TestVersionGated_New(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.6")
+
+	client, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, _, err = client.ServerVersion(ctx)
+	require.NoError(t, err)
+
+	// relayfee has been replaced by mempool.get_info
+	fee, err := client.GetRelayFee(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.00001), fee)
+
+	batch := client.NewBatch()
+	relayFee := electrum.BatchAdd[float64](batch, "blockchain.relayfee")
+	require.NoError(t, batch.Send(ctx))
+
+	_, err = relayFee.Result()
+	assert.ErrorIs(t, err, electrum.ErrDeprecated)
+
+	headers, err := client.GetBlockHeaders(ctx, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "aabb", headers.Headers)
+	assert.Equal(t, uint32(2), headers.Count)
+
+	assert.Equal(t, []string{
+		`server.version ["` + electrum.ClientVersion + `",["1.4","1.6"]]`,
+		`mempool.get_info []`,
+		`blockchain.block.headers [0,2,0]`,
+	}, requests())
+}
+
+func TestHandshake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.4.2")
+
+	client, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, err = client.ServerBanner(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.4.2", client.ProtocolVersion())
+	assert.Equal(t, electrum.StateReady, client.State())
+
+	assert.Equal(t, []string{
+		`server.version ["` + electrum.ClientVersion + `",["1.4","1.6"]]`,
+		`server.banner []`,
+	}, requests())
+}
+
+func TestHandshake_Failed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.2")
+
+	client, err := electrum.NewClientTCP(ctx, addr)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	// the server chose a version outside of the offered range
+	_, _, err = client.ServerVersion(ctx)
+	assert.ErrorIs(t, err, electrum.ErrHandshakeFailed)
+	assert.ErrorIs(t, err, electrum.ErrProtocolMismatch)
+
+	require.Eventually(t, client.IsShutdown, 2*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, client.Err(), electrum.ErrHandshakeFailed)
+
+	assert.Equal(t, []string{
+		`server.version ["` + electrum.ClientVersion + `",["1.4","1.6"]]`,
+	}, requests())
+}
+
+func TestHandshake_Disabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, requests := newVersionServer(t, "1.4.2")
+
+	client, err := electrum.NewClientTCP(ctx, addr, electrum.WithoutHandshake())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, err = client.ServerBanner(ctx)
+	require.NoError(t, err)
+
+	assert.Empty(t, client.ProtocolVersion())
+	assert.Equal(t, []string{`server.banner []`}, requests())
+}
+
+func TestHandshake_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client, err := electrum.NewReconnectingClient(ctx, server.Dial())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	events := client.SubscribeState(ctx)
+	for event := range events {
+		if event.State == electrum.StateReady {
+			break
+		}
+	}
+
+	assert.Equal(t, electrumtest.ProtocolMax, client.ProtocolVersion())
+
+	server.Disconnect()
+
+	for event := range events {
+		if event.State == electrum.StateConnected {
+			break
+		}
+	}
+
+	_, err = client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, electrumtest.ProtocolMax, client.ProtocolVersion())
+}
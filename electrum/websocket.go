@@ -0,0 +1,276 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/zauberhaus/logger"
+)
+
+// WebSocketTransport store information about the WebSocket transport.
+// Every JSON-RPC message is sent and received as a single text frame.
+type WebSocketTransport struct {
+	conn      *websocket.Conn
+	responses chan []byte
+	errors    chan error
+
+	readTimeout    time.Duration
+	maxMessageSize int
+
+	writer *writeQueue
+
+	observer       TransportObserver
+	disconnectOnce sync.Once
+
+	failure  *Atomic[error]
+	failOnce sync.Once
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	log logger.Logger
+}
+
+// NewWebSocketTransport opens a new WebSocket connection to the remote server.
+// The url must use the ws:// or wss:// scheme, config is only used for wss://.
+// HTTP proxies are taken from the environment unless a custom dialer is set.
+func NewWebSocketTransport(ctx context.Context, url string, config *tls.Config, opts ...TransportOption) (*WebSocketTransport, error) {
+	log := logger.GetLogger(ctx)
+	cfg := newTransportConfig(opts)
+
+	if cfg.trustStore != nil {
+		u, err := neturl.Parse(url)
+		if err != nil {
+			return nil, err
+		}
+
+		addr := u.Host
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		}
+
+		config = cfg.tlsConfig(addr, config)
+	}
+
+	dialer := websocket.Dialer{
+		NetDialContext:   cfg.dialer.DialContext,
+		HandshakeTimeout: 45 * time.Second,
+		TLSClientConfig:  config,
+	}
+
+	if _, ok := cfg.dialer.(*net.Dialer); ok {
+		dialer.Proxy = http.ProxyFromEnvironment
+	}
+
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadLimit(int64(cfg.maxMessageSize))
+
+	ws := &WebSocketTransport{
+		conn:           conn,
+		responses:      make(chan []byte),
+		errors:         make(chan error),
+		readTimeout:    cfg.readTimeout,
+		maxMessageSize: cfg.maxMessageSize,
+		observer:       cfg.observer,
+		failure:        MakeAtomic[error](nil),
+		done:           make(chan struct{}),
+		log:            log,
+	}
+
+	ws.observer.Connected(conn.RemoteAddr().String())
+
+	ws.writer = newWriteQueue(cfg.writeQueueSize, cfg.writeTimeout, ws.done, ws.writeFrames, ws.fail)
+
+	go ws.listen()
+
+	return ws, nil
+}
+
+// NewClientWS initialize a new client for remote server and connects to the remote server using WebSocket.
+// addr is either host:port or a complete ws:// url.
+func NewClientWS(ctx context.Context, addr string, opts ...ClientOption) (*Client, error) {
+	transport, err := NewWebSocketTransport(ctx, webSocketURL("ws", addr), nil, newClientConfig(opts).transportOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(ctx, transport, opts...), nil
+}
+
+// NewClientWSS initialize a new client for remote server and connects to the remote server using
+// secure WebSocket. addr is either host:port or a complete wss:// url.
+func NewClientWSS(ctx context.Context, addr string, config *tls.Config, opts ...ClientOption) (*Client, error) {
+	transport, err := NewWebSocketTransport(ctx, webSocketURL("wss", addr), config, newClientConfig(opts).transportOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(ctx, transport, opts...), nil
+}
+
+// DialWS returns a DialFunc connecting to addr using WebSocket.
+func DialWS(addr string, opts ...TransportOption) DialFunc {
+	return func(ctx context.Context) (Transport, error) {
+		return NewWebSocketTransport(ctx, webSocketURL("ws", addr), nil, opts...)
+	}
+}
+
+// DialWSS returns a DialFunc connecting to addr using secure WebSocket.
+func DialWSS(addr string, config *tls.Config, opts ...TransportOption) DialFunc {
+	return func(ctx context.Context) (Transport, error) {
+		return NewWebSocketTransport(ctx, webSocketURL("wss", addr), config, opts...)
+	}
+}
+
+func webSocketURL(scheme string, addr string) string {
+	if strings.Contains(addr, "://") {
+		return addr
+	}
+
+	return scheme + "://" + addr
+}
+
+func (t *WebSocketTransport) listen() {
+	defer t.conn.Close()
+
+	for {
+		if t.readTimeout > 0 {
+			t.conn.SetReadDeadline(time.Now().Add(t.readTimeout))
+		}
+
+		_, msg, err := t.conn.ReadMessage()
+		if errors.Is(err, websocket.ErrReadLimit) {
+			// the connection can not be used after exceeding the read limit
+			err = &MessageTooLargeError{Limit: t.maxMessageSize}
+		}
+
+		if err != nil {
+			// report why the connection has been dropped instead of the read error
+			if failure, ok := Get(t.failure, func(val error) (error, bool) {
+				return val, val != nil
+			}); ok {
+				err = failure
+			}
+
+			t.disconnected(err)
+
+			select {
+			case t.errors <- err:
+			case <-t.done:
+			}
+
+			return
+		}
+
+		t.log.Debugf("%s -> %s", t.conn.RemoteAddr(), msg)
+		t.observer.MessageReceived(len(msg))
+
+		select {
+		case t.responses <- msg:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// SendMessage sends a message to the remote server through the WebSocket transport.
+// Each newline terminated line of body is sent as its own text frame.
+func (t *WebSocketTransport) SendMessage(body []byte) error {
+	return t.SendMessageContext(context.Background(), body)
+}
+
+// SendMessageContext queues a message for the remote server and waits until it has
+// been written.
+func (t *WebSocketTransport) SendMessageContext(ctx context.Context, body []byte) error {
+	t.log.Debugf("%s <- %s", t.conn.RemoteAddr(), body)
+
+	if err := t.writer.send(ctx, body); err != nil {
+		return err
+	}
+
+	t.observer.MessageSent(len(body))
+
+	return nil
+}
+
+func (t *WebSocketTransport) writeFrames(deadline time.Time, bodies [][]byte) (int, error) {
+	t.conn.SetWriteDeadline(deadline)
+
+	for i, body := range bodies {
+		for _, line := range bytes.Split(body, []byte{nl}) {
+			if len(line) == 0 {
+				continue
+			}
+
+			if err := t.conn.WriteMessage(websocket.TextMessage, line); err != nil {
+				return i, err
+			}
+		}
+	}
+
+	return len(bodies), nil
+}
+
+// fail drops the connection after a failed or interrupted write, the listener
+// reports err.
+func (t *WebSocketTransport) fail(err error) {
+	t.failOnce.Do(func() {
+		t.failure.Change(func(error) (error, error) {
+			return err, nil
+		})
+
+		t.conn.Close()
+	})
+}
+
+// Addr returns the address of the remote server.
+func (t *WebSocketTransport) Addr() string {
+	return t.conn.RemoteAddr().String()
+}
+
+// Responses returns chan to WebSocket transport responses.
+func (t *WebSocketTransport) Responses() <-chan []byte {
+	return t.responses
+}
+
+// Errors returns chan to WebSocket transport errors.
+func (t *WebSocketTransport) Errors() <-chan error {
+	return t.errors
+}
+
+// Close sends a close frame and closes the connection.
+func (t *WebSocketTransport) Close() error {
+	var err error
+
+	t.closeOnce.Do(func() {
+		close(t.done)
+		t.disconnected(nil)
+
+		t.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+
+		err = t.conn.Close()
+	})
+
+	return err
+}
+
+// disconnected reports the end of the connection once.
+func (t *WebSocketTransport) disconnected(err error) {
+	t.disconnectOnce.Do(func() {
+		t.observer.Disconnected(err)
+	})
+}
@@ -0,0 +1,141 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// webSocketHandler serves a WebSocket endpoint answering every request with
+// result and pushing a header notification after a headers subscription.
+func webSocketHandler(t *testing.T, result any) http.Handler {
+	upgrader := websocket.Upgrader{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if typ != websocket.TextMessage || strings.Contains(string(msg), "\n") {
+				t.Errorf("unexpected frame: %d %q", typ, msg)
+				return
+			}
+
+			var req struct {
+				ID     uint64 `json:"id"`
+				Method string `json:"method"`
+			}
+			if err := json.Unmarshal(msg, &req); err != nil {
+				t.Errorf("bad request: %v", err)
+				return
+			}
+
+			reply := result
+			if req.Method == "server.version" {
+				reply = []string{"Fake 1.0", electrum.ProtocolMax}
+			}
+
+			resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": reply})
+			if err := conn.WriteMessage(websocket.TextMessage, resp); err != nil {
+				return
+			}
+
+			if req.Method == "blockchain.headers.subscribe" {
+				notif, _ := json.Marshal(map[string]any{
+					"jsonrpc": "2.0",
+					"method":  "blockchain.headers.subscribe",
+					"params":  []any{map[string]any{"height": 2, "hex": "bb"}},
+				})
+				if err := conn.WriteMessage(websocket.TextMessage, notif); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+func newWebSocketServer(t *testing.T, result any) *httptest.Server {
+	srv := httptest.NewServer(webSocketHandler(t, result))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := newWebSocketServer(t, map[string]any{"height": 1, "hex": "aa"})
+
+	client, err := electrum.NewClientWS(ctx, strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, &electrum.SubscribeHeadersResult{Height: 1, Hex: "aa"}, <-headers)
+	assert.Equal(t, &electrum.SubscribeHeadersResult{Height: 2, Hex: "bb"}, <-headers)
+}
+
+func TestWebSocketTransport_TLS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := httptest.NewTLSServer(webSocketHandler(t, "pong"))
+	defer srv.Close()
+
+	url := "wss://" + strings.TrimPrefix(srv.URL, "https://")
+
+	client, err := electrum.NewClientWSS(ctx, url, srv.Client().Transport.(*http.Transport).TLSClientConfig)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", banner)
+}
+
+func TestWebSocketTransport_ServerClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	transport, err := electrum.NewWebSocketTransport(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer transport.Close()
+
+	select {
+	case err := <-transport.Errors():
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error after server closed the connection")
+	}
+}
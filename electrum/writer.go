@@ -0,0 +1,234 @@
+package electrum
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultWriteQueueSize is the number of messages a transport queues for writing.
+	DefaultWriteQueueSize = 256
+
+	// DefaultWriteTimeout limits the time a write may take if the context of the
+	// message has no deadline.
+	DefaultWriteTimeout = 30 * time.Second
+
+	// maxCoalesceSize limits how many bytes of queued messages are merged into one write.
+	maxCoalesceSize = 64 << 10
+)
+
+// ContextSender is implemented by transports whose SendMessage can be cancelled
+// while the message is waiting in the outbound queue.
+type ContextSender interface {
+	SendMessageContext(ctx context.Context, body []byte) error
+}
+
+const (
+	outboundQueued int32 = iota
+	outboundWriting
+	outboundCancelled
+)
+
+type outbound struct {
+	body     []byte
+	deadline time.Time
+	state    atomic.Int32
+	result   chan error
+}
+
+// writeQueue serializes all writes of a connection in a single goroutine. Messages
+// queued while a write is in progress are merged into the next write. A failed or
+// interrupted write leaves a partial message on the connection, so it is dropped.
+type writeQueue struct {
+	queue   chan *outbound
+	done    <-chan struct{}
+	timeout time.Duration
+
+	// flush writes bodies until deadline and returns how many of them have been
+	// written completely. A zero deadline means no deadline.
+	flush func(deadline time.Time, bodies [][]byte) (int, error)
+
+	// abort drops the connection with err, it stops a write in progress.
+	abort func(err error)
+}
+
+func newWriteQueue(size int, timeout time.Duration, done <-chan struct{},
+	flush func(deadline time.Time, bodies [][]byte) (int, error), abort func(err error)) *writeQueue {
+	if size <= 0 {
+		size = DefaultWriteQueueSize
+	}
+
+	q := &writeQueue{
+		queue:   make(chan *outbound, size),
+		done:    done,
+		timeout: timeout,
+		flush:   flush,
+		abort:   abort,
+	}
+
+	go q.run()
+
+	return q
+}
+
+// send queues body and waits until it has been written. The deadline of ctx is the
+// write deadline of the message. If ctx is done while the message is written, the
+// connection is dropped.
+func (q *writeQueue) send(ctx context.Context, body []byte) error {
+	msg := &outbound{
+		body:   body,
+		result: make(chan error, 1),
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		msg.deadline = deadline
+	}
+
+	select {
+	case q.queue <- msg:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.done:
+		return net.ErrClosed
+	}
+
+	select {
+	case err := <-msg.result:
+		return err
+	case <-ctx.Done():
+		if msg.state.CompareAndSwap(outboundQueued, outboundCancelled) {
+			return ctx.Err()
+		}
+
+		select {
+		case err := <-msg.result:
+			return err
+		default:
+		}
+
+		// already being written, stop the write
+		q.abort(fmt.Errorf("write interrupted: %w", ctx.Err()))
+
+		select {
+		case <-msg.result:
+		case <-q.done:
+		}
+
+		return ctx.Err()
+	case <-q.done:
+		return net.ErrClosed
+	}
+}
+
+func (q *writeQueue) run() {
+	for {
+		var msg *outbound
+
+		select {
+		case msg = <-q.queue:
+		case <-q.done:
+			return
+		}
+
+		batch := make([]*outbound, 0, 1)
+		size := 0
+
+		take := func(msg *outbound) {
+			if msg.state.CompareAndSwap(outboundQueued, outboundWriting) {
+				batch = append(batch, msg)
+				size += len(msg.body)
+			}
+		}
+
+		take(msg)
+
+	coalesce:
+		for size < maxCoalesceSize {
+			select {
+			case msg = <-q.queue:
+				take(msg)
+			default:
+				break coalesce
+			}
+		}
+
+		if len(batch) == 0 {
+			continue
+		}
+
+		bodies := make([][]byte, len(batch))
+		for i, msg := range batch {
+			bodies[i] = msg.body
+		}
+
+		written, err := q.flush(q.deadline(batch), bodies)
+		if err != nil {
+			q.abort(err)
+		}
+
+		for i, msg := range batch {
+			if i < written {
+				msg.result <- nil
+			} else {
+				msg.result <- err
+			}
+		}
+	}
+}
+
+// deadline returns the earliest deadline of the messages in batch or, if none of
+// them has one, the deadline of the write timeout.
+func (q *writeQueue) deadline(batch []*outbound) time.Time {
+	var deadline time.Time
+
+	for _, msg := range batch {
+		if !msg.deadline.IsZero() && (deadline.IsZero() || msg.deadline.Before(deadline)) {
+			deadline = msg.deadline
+		}
+	}
+
+	if deadline.IsZero() && q.timeout > 0 {
+		deadline = time.Now().Add(q.timeout)
+	}
+
+	return deadline
+}
+
+// writeConcat writes all bodies with a single call to write and maps a short
+// write back to the messages which made it completely.
+func writeConcat(write func([]byte) (int, error), bodies [][]byte) (int, error) {
+	var buf []byte
+	if len(bodies) == 1 {
+		buf = bodies[0]
+	} else {
+		size := 0
+		for _, body := range bodies {
+			size += len(body)
+		}
+
+		buf = make([]byte, 0, size)
+		for _, body := range bodies {
+			buf = append(buf, body...)
+		}
+	}
+
+	n, err := write(buf)
+	if err == nil {
+		return len(bodies), nil
+	}
+
+	written := 0
+	for _, body := range bodies {
+		if n < len(body) {
+			break
+		}
+
+		n -= len(body)
+		written++
+	}
+
+	return written, err
+}
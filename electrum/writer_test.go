@@ -0,0 +1,274 @@
+package electrum_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zauberhaus/go-electrum/electrum"
+)
+
+// slowConn delays each write to the connection until gate receives a value.
+type slowConn struct {
+	net.Conn
+
+	gate chan struct{}
+}
+
+func (c *slowConn) Write(b []byte) (int, error) {
+	<-c.gate
+	return c.Conn.Write(b)
+}
+
+type slowDialer struct {
+	conn *slowConn
+}
+
+func (d *slowDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	d.conn = &slowConn{Conn: conn, gate: make(chan struct{})}
+	return d.conn, nil
+}
+
+func TestWriteQueue_Coalesces(t *testing.T) {
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var lock sync.Mutex
+	var flushes [][][]byte
+
+	gate := make(chan struct{})
+	queue := electrum.NewWriteQueue(done, func(deadline time.Time, bodies [][]byte) (int, error) {
+		lock.Lock()
+		flushes = append(flushes, bodies)
+		lock.Unlock()
+
+		<-gate
+		return len(bodies), nil
+	})
+
+	flushed := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return len(flushes)
+	}
+
+	// the first message blocks the writer
+	first := make(chan error, 1)
+	go func() {
+		first <- queue.Send(ctx, []byte("first\n"))
+	}()
+
+	require.Eventually(t, func() bool { return flushed() == 1 }, 5*time.Second, time.Millisecond)
+
+	const N = 50
+
+	var wg sync.WaitGroup
+	errs := make([]error, N)
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = queue.Send(ctx, []byte(fmt.Sprintf("msg%d\n", i)))
+		}(i)
+	}
+
+	require.Eventually(t, func() bool { return queue.Queued() == N }, 5*time.Second, time.Millisecond)
+	close(gate)
+
+	require.NoError(t, <-first)
+	wg.Wait()
+
+	for i, err := range errs {
+		assert.NoError(t, err, "message %d", i)
+	}
+
+	// all queued messages went out in a single write
+	require.Equal(t, 2, flushed())
+	assert.Len(t, flushes[1], N)
+}
+
+func TestTCPTransport_CancelQueuedMessage(t *testing.T) {
+	ctx := context.Background()
+
+	received := make(chan []byte, 10)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 1024)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			received <- bytes.Clone(buf[:n])
+		}
+	}()
+
+	dialer := &slowDialer{}
+	transport, err := electrum.NewTCPTransport(ctx, l.Addr().String(), electrum.WithDialer(dialer))
+	require.NoError(t, err)
+	defer transport.Close()
+
+	// the first message blocks the writer
+	first := make(chan error, 1)
+	go func() {
+		first <- transport.SendMessageContext(ctx, []byte("first\n"))
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	// the second message waits in the queue and is cancelled there
+	cctx, ccancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer ccancel()
+
+	err = transport.SendMessageContext(cctx, []byte("second\n"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(dialer.conn.gate)
+	require.NoError(t, <-first)
+
+	assert.Equal(t, "first\n", string(<-received))
+
+	select {
+	case msg := <-received:
+		t.Fatalf("cancelled message was written: %q", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTCPTransport_SendAfterClose(t *testing.T) {
+	ctx := context.Background()
+
+	addr := newTCPServer(t, func(method string, params json.RawMessage) any {
+		return nil
+	})
+
+	transport, err := electrum.NewTCPTransport(ctx, addr)
+	require.NoError(t, err)
+
+	require.NoError(t, transport.SendMessage([]byte("{}\n")))
+	transport.Close()
+
+	assert.ErrorIs(t, transport.SendMessage([]byte("{}\n")), net.ErrClosed)
+}
+
+func TestStreamTransport_WriteTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	// the peer never reads, every write blocks
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	transport := electrum.NewStreamTransport(ctx, conn, electrum.WithWriteTimeout(100*time.Millisecond))
+	defer transport.Close()
+
+	start := time.Now()
+	err := transport.SendMessage([]byte("{}\n"))
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// the connection is dropped
+	select {
+	case err := <-transport.Errors():
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "connection not dropped")
+	}
+}
+
+func TestStreamTransport_WriteCancelled(t *testing.T) {
+	ctx := context.Background()
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	transport := electrum.NewStreamTransport(ctx, conn, electrum.WithWriteTimeout(0))
+	defer transport.Close()
+
+	cctx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := transport.SendMessageContext(cctx, []byte("{}\n"))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	select {
+	case err := <-transport.Errors():
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "connection not dropped")
+	}
+}
+
+func TestClient_WriteStallTimesOut(t *testing.T) {
+	ctx := context.Background()
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	transport := electrum.NewStreamTransport(ctx, conn, electrum.WithWriteTimeout(0))
+	client := electrum.NewClient(ctx, transport, electrum.WithoutHandshake(), electrum.WithTimeout(100*time.Millisecond))
+	defer client.Shutdown()
+
+	_, err := client.ServerBanner(ctx)
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+
+	// the stalled connection is dropped, without reconnecting mode the client shuts down
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "client not shut down")
+	}
+}
+
+func TestClient_WriteStallReconnects(t *testing.T) {
+	ctx := context.Background()
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	second := NewMockTransport()
+	stop := startAutoResponder(second, "banner")
+	defer stop()
+
+	transport := electrum.NewStreamTransport(ctx, conn, electrum.WithWriteTimeout(0))
+	client := electrum.NewClient(ctx, transport,
+		electrum.WithoutHandshake(),
+		electrum.WithTimeout(100*time.Millisecond),
+		electrum.WithReconnect(dialSequence(second)),
+		electrum.WithBackoff(electrum.Backoff{Min: 10 * time.Millisecond}),
+	)
+	defer client.Shutdown()
+
+	_, err := client.ServerBanner(ctx)
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+
+	require.Eventually(t, func() bool {
+		banner, err := client.ServerBanner(ctx)
+		return err == nil && banner == "banner"
+	}, 5*time.Second, 50*time.Millisecond)
+}
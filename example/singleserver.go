@@ -9,7 +9,9 @@ import (
 )
 
 func main() {
-	client, err := electrum.NewClientTCP(context.Background(), "bch.imaginary.cash:50001")
+	// Ping the server every 60 seconds and give up after 3 failed pings in a row
+	client, err := electrum.NewClientTCP(context.Background(), "bch.imaginary.cash:50001",
+		electrum.WithKeepAlive(60*time.Second, 3))
 
 	if err != nil {
 		log.Fatal(err)
@@ -21,12 +23,6 @@ func main() {
 	}
 	log.Printf("Server version: %s [Protocol %s]", serverVer, protocolVer)
 
-	go func() {
-		for {
-			if err := client.Ping(context.Background()); err != nil {
-				log.Fatal(err)
-			}
-			time.Sleep(60 * time.Second)
-		}
-	}()
+	<-client.Done()
+	log.Fatal(client.Err())
 }